@@ -11,7 +11,7 @@ export default function Home() {
 
   useEffect(() => {
     const cookies = document.cookie.split(';');
-    const hasToken = cookies.some(cookie => cookie.trim().startsWith('access_token='));
+    const hasToken = cookies.some(cookie => cookie.trim().startsWith('signed_in='));
     setHasAccessToken(hasToken);
   }, []);
 
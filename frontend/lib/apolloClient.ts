@@ -1,11 +1,21 @@
 import { ApolloClient, InMemoryCache, HttpLink, ServerError, from } from '@apollo/client';
 import { onError } from '@apollo/client/link/error';
+import { createPersistedQueryLink } from '@apollo/client/link/persisted-queries';
 
 const httpLink = new HttpLink({
   uri: '/query',
   credentials: 'include',
 });
 
+// Queries are sent by hash, a server started with PERSISTED_QUERIES_ONLY only runs the ones registered with
+// server/cmd/register-queries
+const persistedQueryLink = createPersistedQueryLink({
+  sha256: async (query: string) => {
+    const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(query));
+    return Array.from(new Uint8Array(digest), byte => byte.toString(16).padStart(2, '0')).join('');
+  },
+});
+
 const errorLink = onError(({ graphQLErrors, networkError }) => {
   if ((networkError && (networkError as ServerError).statusCode === 401) || graphQLErrors?.some(error => error?.extensions?.code === 'UNAUTHENTICATED')) {
     window.location.href = '/';
@@ -13,7 +23,7 @@ const errorLink = onError(({ graphQLErrors, networkError }) => {
 });
 
 const client = new ApolloClient({
-  link: from([errorLink, httpLink]),
+  link: from([errorLink, persistedQueryLink, httpLink]),
   cache: new InMemoryCache(),
 });
 
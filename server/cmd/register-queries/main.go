@@ -0,0 +1,85 @@
+// Command register-queries adds the queries of the frontend to the persisted query allowlist, for servers
+// started with PERSISTED_QUERIES_ONLY=true. Run it with DATABASE_URL (or CONFIG_FILE) set before deploying a new frontend:
+//
+//	go run ./cmd/register-queries ../frontend/graphql/*.ts
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Azanul/Next-Watch/graph"
+	"github.com/Azanul/Next-Watch/internal/config"
+	"github.com/Azanul/Next-Watch/internal/database"
+	"github.com/Azanul/Next-Watch/internal/persisted"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func main() {
+	typename := flag.Bool("typename", true, "add __typename to selection sets like Apollo Client does before hashing")
+	dryRun := flag.Bool("dry-run", false, "print the hashes without registering the queries")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Queries that don't match the schema would never run, so they are refused before anything is registered
+	schema := graph.NewExecutableSchema(graph.Config{}).Schema()
+	var queries []string
+	var names []string
+	for _, path := range flag.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		templates, err := persisted.ExtractQueries(string(source))
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+
+		for _, template := range templates {
+			doc, errs := gqlparser.LoadQuery(schema, template)
+			if errs != nil {
+				log.Fatalf("%s: %v", path, errs)
+			}
+			if *typename {
+				persisted.AddTypename(doc)
+			}
+
+			var operations []string
+			for _, op := range doc.Operations {
+				operations = append(operations, op.Name)
+			}
+			queries = append(queries, persisted.Print(doc))
+			names = append(names, strings.Join(operations, ", "))
+		}
+	}
+
+	var store *persisted.PostgresStore
+	if !*dryRun {
+		databaseURL, err := config.LoadDatabaseURL(os.Getenv)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = persisted.NewPostgresStore(database.ConnectDB(databaseURL), 1)
+	}
+	for i, query := range queries {
+		hash := persisted.Hash(query)
+		if store != nil {
+			var err error
+			if hash, err = store.Register(context.Background(), query); err != nil {
+				log.Fatalf("Failed to register %s: %v", names[i], err)
+			}
+		}
+		fmt.Printf("%s %s\n", hash, names[i])
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS rating_count,
+    DROP COLUMN IF EXISTS score_mean,
+    DROP COLUMN IF EXISTS score_m2;
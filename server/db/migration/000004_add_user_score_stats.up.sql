@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN score_mean DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN score_m2 DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+UPDATE users
+SET rating_count = stats.rating_count,
+    score_mean = stats.score_mean,
+    score_m2 = stats.score_m2
+FROM (
+    SELECT user_id,
+           COUNT(*) AS rating_count,
+           AVG(score) AS score_mean,
+           COALESCE(VAR_POP(score), 0) * COUNT(*) AS score_m2
+    FROM ratings
+    GROUP BY user_id
+) AS stats
+WHERE users.id = stats.user_id;
@@ -0,0 +1,7 @@
+CREATE TABLE oauth_states (
+    state TEXT PRIMARY KEY,
+    code_verifier TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX oauth_states_expires_at_idx ON oauth_states (expires_at);
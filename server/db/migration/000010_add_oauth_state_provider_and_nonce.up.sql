@@ -0,0 +1,2 @@
+ALTER TABLE oauth_states ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth_states ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
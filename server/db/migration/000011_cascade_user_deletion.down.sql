@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS data_exports;
+
+ALTER TABLE ratings DROP CONSTRAINT ratings_user_id_fkey;
+ALTER TABLE ratings ADD CONSTRAINT ratings_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_states DROP COLUMN IF EXISTS link_user_id;
+
+DROP TABLE IF EXISTS user_identities;
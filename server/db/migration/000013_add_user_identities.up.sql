@@ -0,0 +1,16 @@
+-- Provider accounts linked to a user. The subject is the provider's stable ID for the account,
+-- unlike the email which the user can change on the provider's side.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- Set when a signed in user starts a sign-in to link another provider
+ALTER TABLE oauth_states ADD COLUMN link_user_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- Queries sent with automatic persisted queries, shared by every replica. Registered queries were added
+-- from the frontend's query files and are the only ones accepted when the API runs in allowlist mode.
+CREATE TABLE persisted_queries (
+    hash TEXT PRIMARY KEY,
+    query TEXT NOT NULL,
+    registered BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
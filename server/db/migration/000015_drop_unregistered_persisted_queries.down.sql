@@ -0,0 +1,2 @@
+-- The deleted client queries are sent again by clients that still use them
+SELECT 1;
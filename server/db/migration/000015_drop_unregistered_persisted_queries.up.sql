@@ -0,0 +1,2 @@
+-- Queries persisted by clients are only kept in process now, the table holds registered queries
+DELETE FROM persisted_queries WHERE registered = FALSE;
@@ -0,0 +1,2 @@
+-- Refresh tokens providers granted for offline access, sealed with the ENCRYPTION_KEYS keyring
+ALTER TABLE user_identities ADD COLUMN refresh_token TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"github.com/Azanul/Next-Watch/graph/model"
+	"github.com/Azanul/Next-Watch/internal/services"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// clampPageSize is the page size connection resolvers actually use for the one requested,
+// the default when none is given and at most maxPageSize
+func clampPageSize(requested int) int {
+	if requested < 1 {
+		return defaultPageSize
+	}
+	if requested > maxPageSize {
+		return maxPageSize
+	}
+	return requested
+}
+
+// Complexity prices fields for the query cost limit. Fields cost 1 plus their selections by default,
+// connections cost their selections once per item on a page, since every item resolves them again.
+func Complexity() ComplexityRoot {
+	var c ComplexityRoot
+
+	c.Query.Movies = func(childComplexity int, page int, size int) int {
+		return 1 + clampPageSize(size)*childComplexity
+	}
+	c.Query.SearchMovies = func(childComplexity int, query string, page int, size int) int {
+		return 1 + clampPageSize(size)*childComplexity
+	}
+	c.Query.Recommendations = func(childComplexity int, page int, size int) int {
+		return 1 + clampPageSize(size)*childComplexity
+	}
+	c.Query.Nodes = func(childComplexity int, ids []string) int {
+		return 1 + len(ids)*childComplexity
+	}
+	// A gateway fetching entities resolves the selection once per representation
+	c.Query.__resolve_entities = func(childComplexity int, representations []map[string]interface{}) int {
+		return 1 + len(representations)*childComplexity
+	}
+	c.Query.Users = func(childComplexity int, search *string, page int, size int) int {
+		return 1 + clampPageSize(size)*childComplexity
+	}
+	c.Mutation.RateMovies = func(childComplexity int, input []*model.RatingInput) int {
+		return 1 + len(input)*childComplexity
+	}
+	c.Subscription.RecommendationsUpdated = func(childComplexity int, size int) int {
+		return 1 + clampPageSize(size)*childComplexity
+	}
+	c.User.TopGenres = func(childComplexity int) int {
+		return 1 + services.TopGenresLimit*childComplexity
+	}
+	return c
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClampPageSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested int
+		want      int
+	}{
+		{name: "Requested", requested: 25, want: 25},
+		{name: "Missing Uses Default", requested: 0, want: defaultPageSize},
+		{name: "Negative Uses Default", requested: -5, want: defaultPageSize},
+		{name: "Max", requested: maxPageSize, want: maxPageSize},
+		{name: "Over Max Clamps To Max", requested: maxPageSize + 1, want: maxPageSize},
+		{name: "Huge Clamps To Max", requested: 1 << 30, want: maxPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clampPageSize(tt.requested))
+		})
+	}
+}
+
+func TestComplexity_PricesClampedPageSize(t *testing.T) {
+	c := Complexity()
+
+	// A page of 1000 resolves at most maxPageSize items, and is priced that way
+	assert.Equal(t, 1+maxPageSize*2, c.Query.Movies(2, 1, 1000))
+	assert.Equal(t, 1+25*2, c.Query.Movies(2, 1, 25))
+	assert.Equal(t, 1+defaultPageSize*2, c.Query.Movies(2, 1, 0))
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/Azanul/Next-Watch/graph/model"
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/policy"
+	"github.com/Azanul/Next-Watch/internal/repository"
+)
+
+// userToModel converts a user to its GraphQL model, leaving out private fields the viewer may not see
+func userToModel(p *policy.Policy, user *models.User, viewer *models.User) *model.User {
+	u := &model.User{
+		ID:        globalid.Encode(globalid.User, user.ID),
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+	}
+	if user.AvatarURL != "" {
+		avatarURL := user.AvatarURL
+		u.AvatarURL = &avatarURL
+	}
+	if p.CanViewPrivateFields(viewer, user) {
+		email, role := user.Email, user.Role
+		u.Email, u.Role = &email, &role
+		u.SuspendedAt = user.SuspendedAt
+	}
+	return u
+}
+
+func movieToModel(movie *models.Movie) *model.Movie {
+	return &model.Movie{
+		ID:    globalid.Encode(globalid.Movie, movie.ID),
+		Title: movie.Title,
+		Genre: movie.Genre,
+		Year:  movie.Year,
+		Wiki:  movie.Wiki,
+		Plot:  movie.Plot,
+		Cast:  movie.Cast,
+	}
+}
+
+func movieConnectionToModel(moviePage *repository.MoviePage) *model.MovieConnection {
+	edges := make([]*model.MovieEdge, len(moviePage.Movies))
+	for i, movie := range moviePage.Movies {
+		edges[i] = &model.MovieEdge{Node: movieToModel(movie)}
+	}
+	return &model.MovieConnection{
+		Edges: edges,
+		PageInfo: &model.PageInfo{
+			HasNextPage:     moviePage.HasNextPage,
+			HasPreviousPage: moviePage.HasPreviousPage,
+		},
+		TotalCount: moviePage.TotalCount,
+	}
+}
+
+// ratingToModel keeps the IDs of the rating's user and movie, their resolvers load the rest
+func ratingToModel(rating *models.Rating) *model.Rating {
+	return &model.Rating{
+		ID:      globalid.Encode(globalid.Rating, rating.ID),
+		UserID:  rating.UserID,
+		MovieID: rating.MovieID,
+		Score:   float64(rating.Score),
+	}
+}
+
+// itemErrorToModel reports why an item of a batch failed. Only errors with a code are meant for the client.
+func itemErrorToModel(err error) *model.ItemError {
+	code := apperr.CodeOf(err)
+	if code == apperr.CodeInternal {
+		return &model.ItemError{Code: string(code), Message: "internal server error"}
+	}
+	return &model.ItemError{Code: string(code), Message: err.Error()}
+}
+
+func accessTokenToModel(token *models.PersonalAccessToken) *model.PersonalAccessToken {
+	scopes := make([]model.TokenScope, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = model.TokenScope(strings.ToUpper(scope))
+	}
+	return &model.PersonalAccessToken{
+		ID:         globalid.Encode(globalid.PersonalAccessToken, token.ID),
+		Name:       token.Name,
+		Scopes:     scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
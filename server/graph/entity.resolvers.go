@@ -0,0 +1,119 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.54
+
+import (
+	"context"
+
+	"github.com/Azanul/Next-Watch/graph/model"
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+)
+
+// FindManyMovieByIDs is the resolver for the findManyMovieByIDs field.
+func (r *entityResolver) FindManyMovieByIDs(ctx context.Context, reps []*model.MovieByIDsInput) ([]*model.Movie, error) {
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	movieIDs, err := decodeIDs(globalid.Movie, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	movies, err := r.MovieService.GetMoviesByIDs(ctx, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Movie, len(movies))
+	for _, movie := range movies {
+		byID[movie.ID] = movie
+	}
+
+	// Movies that don't exist resolve to null
+	entities := make([]*model.Movie, len(reps))
+	for i, movieID := range movieIDs {
+		if movie := byID[movieID]; movie != nil {
+			entities[i] = movieToModel(movie)
+		}
+	}
+	return entities, nil
+}
+
+// FindManyRatingByIDs is the resolver for the findManyRatingByIDs field.
+func (r *entityResolver) FindManyRatingByIDs(ctx context.Context, reps []*model.RatingByIDsInput) ([]*model.Rating, error) {
+	// Like node(id:), ratings are only returned to signed in users
+	if _, err := auth.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	ratingIDs, err := decodeIDs(globalid.Rating, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := r.RatingService.GetRatingsByIDs(ctx, ratingIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Rating, len(ratings))
+	for _, rating := range ratings {
+		byID[rating.ID] = rating
+	}
+
+	entities := make([]*model.Rating, len(reps))
+	for i, ratingID := range ratingIDs {
+		if rating := byID[ratingID]; rating != nil {
+			entities[i] = ratingToModel(rating)
+		}
+	}
+	return entities, nil
+}
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*model.User, error) {
+	// Like node(id:), users are only returned to signed in users
+	currentUser, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	userIDs, err := decodeIDs(globalid.User, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.UserService.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	entities := make([]*model.User, len(reps))
+	for i, userID := range userIDs {
+		if user := byID[userID]; user != nil {
+			entities[i] = userToModel(r.Policy, user, currentUser)
+		}
+	}
+	return entities, nil
+}
+
+// Entity returns EntityResolver implementation.
+func (r *Resolver) Entity() EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
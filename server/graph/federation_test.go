@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/Azanul/Next-Watch/internal/policy"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/Azanul/Next-Watch/internal/services"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// federationDefinitions are the federation 2 definitions a gateway adds to every subgraph before reading its schema
+const federationDefinitions = `
+directive @link(url: String!, import: [String!]) repeatable on SCHEMA
+directive @key(fields: FieldSet!, resolvable: Boolean = true) repeatable on OBJECT | INTERFACE
+scalar FieldSet
+`
+
+// federationDirectives are the names federation 2 reserves, a subgraph defining its own directive with one of them fails composition
+var federationDirectives = []string{
+	"key", "requires", "provides", "external", "shareable", "override", "inaccessible", "tag", "extends",
+	"interfaceObject", "composeDirective", "authenticated", "requiresScopes", "policy",
+}
+
+func newFederationTestServer(t *testing.T) (*handler.Server, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	movieRepo := repository.NewMovieRepository(db)
+	ratingRepo := repository.NewRatingRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	resolver := &Resolver{
+		MovieService:  *services.NewMovieService(movieRepo),
+		RatingService: *services.NewRatingService(ratingRepo, movieRepo, userRepo),
+		UserService:   *services.NewUserService(userRepo, ratingRepo),
+		Policy:        policy.Default(),
+	}
+
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: resolver, Complexity: Complexity()}))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(apperr.Presenter)
+	// The gateway reads the subgraph's schema from _service, which is served like introspection
+	srv.Use(extension.Introspection{})
+	return srv, mock
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, srv http.Handler, query string, variables map[string]interface{}) graphQLResponse {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/query", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp graphQLResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestFederation_ServiceSDL(t *testing.T) {
+	srv, _ := newFederationTestServer(t)
+
+	resp := execute(t, srv, `{ _service { sdl } }`, nil)
+	require.Empty(t, resp.Errors)
+	var service struct {
+		SDL string `json:"sdl"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["_service"], &service))
+
+	schema, err := gqlparser.LoadSchema(
+		&ast.Source{Name: "federation.graphqls", Input: federationDefinitions, BuiltIn: true},
+		&ast.Source{Name: "movies.graphqls", Input: service.SDL},
+	)
+	require.NoError(t, err)
+
+	doc, err := parser.ParseSchema(&ast.Source{Input: service.SDL})
+	require.NoError(t, err)
+	var link *ast.Directive
+	for _, extension := range doc.SchemaExtension {
+		if d := extension.Directives.ForName("link"); d != nil {
+			link = d
+		}
+	}
+	require.NotNil(t, link, "the subgraph must opt into federation 2 with @link")
+	assert.Equal(t, "https://specs.apollo.dev/federation/v2.3", link.Arguments.ForName("url").Value.Raw)
+
+	for _, name := range federationDirectives {
+		assert.Nil(t, doc.Directives.ForName(name), "@%s is reserved by federation", name)
+	}
+
+	for _, entity := range []string{"Movie", "User", "Rating"} {
+		def := schema.Types[entity]
+		require.NotNil(t, def, entity)
+		key := def.Directives.ForName("key")
+		require.NotNil(t, key, "%s must be an entity", entity)
+		assert.Equal(t, "id", key.Arguments.ForName("fields").Value.Raw)
+		assert.Equal(t, "ID!", def.Fields.ForName("id").Type.String())
+	}
+}
+
+func TestFederation_ResolvesEntitiesInOneBatch(t *testing.T) {
+	srv, mock := newFederationTestServer(t)
+
+	heat, missing, alien := uuid.New(), uuid.New(), uuid.New()
+	rows := sqlmock.NewRows([]string{"id", "title", "genre", "year", "wiki", "plot", "director", "cast"}).
+		AddRow(alien, "Alien", "Horror", 1979, "", "", "", "").
+		AddRow(heat, "Heat", "Crime", 1995, "", "", "", "")
+	mock.ExpectQuery("^SELECT (.+) FROM movies WHERE id = ANY").
+		WithArgs(pq.Array([]string{heat.String(), missing.String(), alien.String()})).
+		WillReturnRows(rows)
+
+	// What a gateway sends after the reviews subgraph returned the movies of some reviews
+	representations := []map[string]interface{}{
+		{"__typename": "Movie", "id": globalid.Encode(globalid.Movie, heat)},
+		{"__typename": "Movie", "id": globalid.Encode(globalid.Movie, missing)},
+		{"__typename": "Movie", "id": globalid.Encode(globalid.Movie, alien)},
+	}
+	resp := execute(t, srv, `query($representations: [_Any!]!) {
+		_entities(representations: $representations) { ... on Movie { id title } }
+	}`, map[string]interface{}{"representations": representations})
+	require.Empty(t, resp.Errors)
+
+	var entities []*struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["_entities"], &entities))
+	require.Len(t, entities, 3)
+	assert.Equal(t, "Heat", entities[0].Title)
+	assert.Equal(t, representations[0]["id"], entities[0].ID)
+	assert.Nil(t, entities[1])
+	assert.Equal(t, "Alien", entities[2].Title)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFederation_UserEntitiesNeedSignIn(t *testing.T) {
+	srv, mock := newFederationTestServer(t)
+
+	resp := execute(t, srv, `query($representations: [_Any!]!) {
+		_entities(representations: $representations) { ... on User { name } }
+	}`, map[string]interface{}{"representations": []map[string]interface{}{
+		{"__typename": "User", "id": globalid.Encode(globalid.User, uuid.New())},
+	}})
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, string(apperr.CodeUnauthenticated), resp.Errors[0].Extensions["code"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
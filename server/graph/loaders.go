@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/Azanul/Next-Watch/internal/dataloader"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Loaders batch the lookups of field resolvers, so e.g. the movies of a list of ratings are read in one query
+type Loaders struct {
+	Users           *dataloader.Loader[uuid.UUID, *models.User]
+	Movies          *dataloader.Loader[uuid.UUID, *models.Movie]
+	RatingSummaries *dataloader.Loader[uuid.UUID, *repository.RatingSummary]
+}
+
+func (r *Resolver) newLoaders() *Loaders {
+	return &Loaders{
+		Users: dataloader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.User, error) {
+			users, err := r.UserService.GetUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.User, len(users))
+			for _, user := range users {
+				byID[user.ID] = user
+			}
+			return byID, nil
+		}),
+		Movies: dataloader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Movie, error) {
+			movies, err := r.MovieService.GetMoviesByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.Movie, len(movies))
+			for _, movie := range movies {
+				byID[movie.ID] = movie
+			}
+			return byID, nil
+		}),
+		RatingSummaries: dataloader.New(r.UserService.GetRatingSummaries),
+	}
+}
+
+// LoaderMiddleware gives every response its own loaders. They cache what they load,
+// so sharing them between operations would serve stale data and leak it between users.
+// A subscription sends a response per event, each of them gets fresh loaders too.
+func (r *Resolver) LoaderMiddleware(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(context.WithValue(ctx, "loaders", r.newLoaders()))
+}
+
+// loaders returns the response's loaders, or unshared ones when the middleware isn't installed
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if loaders, ok := ctx.Value("loaders").(*Loaders); ok {
+		return loaders
+	}
+	return r.newLoaders()
+}
@@ -2,6 +2,46 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Node interface {
+	IsNode()
+	GetID() string
+}
+
+type CreatedPersonalAccessToken struct {
+	Token               string               `json:"token"`
+	PersonalAccessToken *PersonalAccessToken `json:"personalAccessToken"`
+}
+
+type DataExport struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type GenreSummary struct {
+	Genre        string  `json:"genre"`
+	RatingCount  int     `json:"ratingCount"`
+	AverageScore float64 `json:"averageScore"`
+}
+
+type ItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type LinkedIdentity struct {
+	ID        string    `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 type Movie struct {
 	ID    string `json:"id"`
 	Title string `json:"title"`
@@ -12,6 +52,15 @@ type Movie struct {
 	Cast  string `json:"cast"`
 }
 
+func (Movie) IsNode()            {}
+func (this Movie) GetID() string { return this.ID }
+
+func (Movie) IsEntity() {}
+
+type MovieByIDsInput struct {
+	ID string `json:"ID"`
+}
+
 type MovieConnection struct {
 	Edges      []*MovieEdge `json:"edges"`
 	PageInfo   *PageInfo    `json:"pageInfo"`
@@ -31,6 +80,12 @@ type MovieInput struct {
 	Cast  string `json:"cast"`
 }
 
+type MovieRatingStats struct {
+	MovieID      string  `json:"movieId"`
+	RatingCount  int     `json:"ratingCount"`
+	AverageScore float64 `json:"averageScore"`
+}
+
 type Mutation struct {
 }
 
@@ -39,19 +94,168 @@ type PageInfo struct {
 	HasPreviousPage bool `json:"hasPreviousPage"`
 }
 
+type PersonalAccessToken struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Scopes     []TokenScope `json:"scopes"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	ExpiresAt  *time.Time   `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time   `json:"lastUsedAt,omitempty"`
+}
+
 type Query struct {
 }
 
-type Rating struct {
-	ID    string  `json:"id"`
-	User  *User   `json:"user"`
-	Movie *Movie  `json:"movie"`
-	Score float64 `json:"score"`
+type RateMoviesResult struct {
+	MovieID string     `json:"movieId"`
+	Rating  *Rating    `json:"rating,omitempty"`
+	Error   *ItemError `json:"error,omitempty"`
+}
+
+type RatingByIDsInput struct {
+	ID string `json:"ID"`
+}
+
+type RatingImportResult struct {
+	Imported  int                   `json:"imported"`
+	Unmatched []*UnmatchedImportRow `json:"unmatched"`
+}
+
+type RatingInput struct {
+	MovieID string  `json:"movieId"`
+	Score   float64 `json:"score"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type Subscription struct {
+}
+
+type UnmatchedImportRow struct {
+	Line   int    `json:"line"`
+	Title  string `json:"title"`
+	Year   *int   `json:"year,omitempty"`
+	Reason string `json:"reason"`
 }
 
 type User struct {
-	ID           string `json:"id"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"passwordHash"`
-	Role         string `json:"role"`
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	AvatarURL    *string         `json:"avatarUrl,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	RatingCount  int             `json:"ratingCount"`
+	AverageScore float64         `json:"averageScore"`
+	TopGenres    []*GenreSummary `json:"topGenres"`
+	Email        *string         `json:"email,omitempty"`
+	Role         *string         `json:"role,omitempty"`
+	SuspendedAt  *time.Time      `json:"suspendedAt,omitempty"`
+}
+
+func (User) IsNode()            {}
+func (this User) GetID() string { return this.ID }
+
+func (User) IsEntity() {}
+
+type UserByIDsInput struct {
+	ID string `json:"ID"`
+}
+
+type UserConnection struct {
+	Edges      []*UserEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type UserEdge struct {
+	Node *User `json:"node"`
+}
+
+type RatingImportFormat string
+
+const (
+	RatingImportFormatLetterboxd RatingImportFormat = "LETTERBOXD"
+	RatingImportFormatImdb       RatingImportFormat = "IMDB"
+)
+
+var AllRatingImportFormat = []RatingImportFormat{
+	RatingImportFormatLetterboxd,
+	RatingImportFormatImdb,
+}
+
+func (e RatingImportFormat) IsValid() bool {
+	switch e {
+	case RatingImportFormatLetterboxd, RatingImportFormatImdb:
+		return true
+	}
+	return false
+}
+
+func (e RatingImportFormat) String() string {
+	return string(e)
+}
+
+func (e *RatingImportFormat) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RatingImportFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RatingImportFormat", str)
+	}
+	return nil
+}
+
+func (e RatingImportFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TokenScope string
+
+const (
+	TokenScopeRead  TokenScope = "READ"
+	TokenScopeWrite TokenScope = "WRITE"
+)
+
+var AllTokenScope = []TokenScope{
+	TokenScopeRead,
+	TokenScopeWrite,
+}
+
+func (e TokenScope) IsValid() bool {
+	switch e {
+	case TokenScopeRead, TokenScopeWrite:
+		return true
+	}
+	return false
+}
+
+func (e TokenScope) String() string {
+	return string(e)
+}
+
+func (e *TokenScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TokenScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TokenScope", str)
+	}
+	return nil
+}
+
+func (e TokenScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
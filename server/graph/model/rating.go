@@ -0,0 +1,18 @@
+package model
+
+import "github.com/google/uuid"
+
+// Rating is written by hand instead of generated so it keeps the IDs of its user and movie,
+// which the Rating.user and Rating.movie resolvers load through dataloaders
+type Rating struct {
+	ID      string    `json:"id"`
+	UserID  uuid.UUID `json:"-"`
+	MovieID uuid.UUID `json:"-"`
+	Score   float64   `json:"score"`
+}
+
+func (Rating) IsNode() {}
+
+func (this Rating) GetID() string { return this.ID }
+
+func (Rating) IsEntity() {}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/Azanul/Next-Watch/graph/model"
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/google/uuid"
+)
+
+// maxNodes is how many IDs a nodes query may look up
+const maxNodes = 100
+
+// node loads the object with a global ID, or returns nil when there is none
+func (r *Resolver) node(ctx context.Context, id string) (model.Node, error) {
+	typeName, objectID, err := globalid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typeName {
+	case globalid.Movie:
+		movie, err := r.loaders(ctx).Movies.Load(ctx, objectID)
+		if err != nil || movie == nil {
+			return nil, err
+		}
+		return movieToModel(movie), nil
+	case globalid.User:
+		currentUser, err := auth.RequireUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		user, err := r.loaders(ctx).Users.Load(ctx, objectID)
+		if err != nil || user == nil {
+			return nil, err
+		}
+		return userToModel(r.Policy, user, currentUser), nil
+	case globalid.Rating:
+		if _, err := auth.RequireUser(ctx); err != nil {
+			return nil, err
+		}
+		rating, err := r.RatingService.GetRatingByID(ctx, objectID)
+		if apperr.CodeOf(err) == apperr.CodeNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ratingToModel(rating), nil
+	}
+	// Sessions, tokens and identities have IDs but can't be refetched on their own
+	return nil, nil
+}
+
+// decodeIDs decodes global IDs that must all be of typeName, as in the representations of federated entities
+func decodeIDs(typeName string, ids []string) ([]uuid.UUID, error) {
+	decoded := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		objectID, err := globalid.Decode(typeName, id)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = objectID
+	}
+	return decoded, nil
+}
@@ -1,6 +1,9 @@
 package graph
 
-import "github.com/Azanul/Next-Watch/internal/services"
+import (
+	"github.com/Azanul/Next-Watch/internal/policy"
+	"github.com/Azanul/Next-Watch/internal/services"
+)
 
 // This file will not be regenerated automatically.
 //
@@ -10,4 +13,13 @@ type Resolver struct {
 	services.RatingService
 	services.MovieService
 	services.RecommendationService
+	services.ImportService
+	services.UserService
+	services.SessionService
+	services.AccessTokenService
+	services.DataExportService
+	services.IdentityService
+	*services.LiveService
+
+	Policy *policy.Policy
 }
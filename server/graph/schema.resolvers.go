@@ -6,12 +6,19 @@ package graph
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/Azanul/Next-Watch/graph/model"
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/auth"
-	"github.com/google/uuid"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/Azanul/Next-Watch/internal/services"
 )
 
 // RateMovie is the resolver for the rateMovie field.
@@ -22,12 +29,12 @@ func (r *mutationResolver) RateMovie(ctx context.Context, movieID string, score
 	}
 
 	// Validate inputs
-	var movieUUID uuid.UUID
-	if movieUUID, err = uuid.Parse(movieID); err != nil {
-		return nil, errors.New("invalid movie id")
+	movieUUID, err := globalid.Decode(globalid.Movie, movieID)
+	if err != nil {
+		return nil, err
 	}
 	if score < 0 || score > 5 {
-		return nil, errors.New("rating score must be between 0 and 5")
+		return nil, apperr.Validation("rating score must be between 0 and 5")
 	}
 
 	// Call service to rate movie
@@ -37,12 +44,47 @@ func (r *mutationResolver) RateMovie(ctx context.Context, movieID string, score
 	}
 
 	// Convert internal model to GraphQL model
-	return &model.Rating{
-		ID:    rating.ID.String(),
-		User:  &model.User{ID: rating.UserID.String()},
-		Movie: &model.Movie{ID: rating.MovieID.String()},
-		Score: float64(rating.Score),
-	}, nil
+	return ratingToModel(rating), nil
+}
+
+// RateMovies is the resolver for the rateMovies field.
+func (r *mutationResolver) RateMovies(ctx context.Context, input []*model.RatingInput) ([]*model.RateMoviesResult, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(input) > services.MaxBatchRatings {
+		return nil, apperr.Validation(fmt.Sprintf("at most %d movies can be rated at once", services.MaxBatchRatings))
+	}
+
+	// Items with an invalid ID are reported like any other invalid item
+	results := make([]*model.RateMoviesResult, len(input))
+	var ratingInputs []services.RatingInput
+	var inputIndexes []int
+	for i, item := range input {
+		results[i] = &model.RateMoviesResult{MovieID: item.MovieID}
+		movieID, err := globalid.Decode(globalid.Movie, item.MovieID)
+		if err != nil {
+			results[i].Error = itemErrorToModel(err)
+			continue
+		}
+		ratingInputs = append(ratingInputs, services.RatingInput{MovieID: movieID, Score: float32(item.Score)})
+		inputIndexes = append(inputIndexes, i)
+	}
+
+	ratingResults, err := r.RatingService.RateMovies(ctx, currentUser, ratingInputs)
+	if err != nil {
+		return nil, err
+	}
+	for j, ratingResult := range ratingResults {
+		result := results[inputIndexes[j]]
+		if ratingResult.Err != nil {
+			result.Error = itemErrorToModel(ratingResult.Err)
+			continue
+		}
+		result.Rating = ratingToModel(ratingResult.Rating)
+	}
+	return results, nil
 }
 
 // DeleteRating is the resolver for the deleteRating field.
@@ -53,9 +95,9 @@ func (r *mutationResolver) DeleteRating(ctx context.Context, id string) (bool, e
 		return false, err
 	}
 
-	ratingID, err := uuid.Parse(id)
+	ratingID, err := globalid.Decode(globalid.Rating, id)
 	if err != nil {
-		return false, errors.New("invalid rating ID")
+		return false, err
 	}
 
 	// Fetch the rating
@@ -63,27 +105,273 @@ func (r *mutationResolver) DeleteRating(ctx context.Context, id string) (bool, e
 	if err != nil {
 		return false, err
 	}
-	if rating == nil {
-		return false, errors.New("rating not found")
-	}
 
-	// Check if the user is authorized to delete this rating
-	isAdmin := currentUser.Role == "ADMIN"
-	isOwner := rating.UserID == currentUser.ID
-
-	if !isAdmin && !isOwner {
-		return false, errors.New("not authorized to delete this rating")
+	// Owners may delete their own ratings, moderators anyone's
+	if !r.Policy.CanDeleteRating(currentUser, rating) {
+		return false, apperr.Forbidden("not authorized to delete this rating")
 	}
 
 	// Delete the rating
 	return r.RatingService.DeleteRating(ctx, ratingID)
 }
 
+// ImportRatings is the resolver for the importRatings field.
+func (r *mutationResolver) ImportRatings(ctx context.Context, file graphql.Upload, format *model.RatingImportFormat) (*model.RatingImportResult, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var importFormat services.RatingImportFormat
+	if format != nil {
+		switch *format {
+		case model.RatingImportFormatLetterboxd:
+			importFormat = services.LetterboxdFormat
+		case model.RatingImportFormatImdb:
+			importFormat = services.IMDbFormat
+		}
+	}
+
+	result, err := r.ImportService.ImportRatings(ctx, currentUser, file.File, importFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched := make([]*model.UnmatchedImportRow, len(result.Unmatched))
+	for i, row := range result.Unmatched {
+		unmatched[i] = &model.UnmatchedImportRow{
+			Line:   row.Line,
+			Title:  row.Title,
+			Reason: row.Reason,
+		}
+		if row.Year != 0 {
+			year := row.Year
+			unmatched[i].Year = &year
+		}
+	}
+
+	return &model.RatingImportResult{
+		Imported:  result.Imported,
+		Unmatched: unmatched,
+	}, nil
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	sessionID, err := globalid.Decode(globalid.Session, id)
+	if err != nil {
+		return false, err
+	}
+
+	return r.SessionService.RevokeSession(ctx, currentUser.ID, sessionID)
+}
+
+// CreateAccessToken is the resolver for the createAccessToken field.
+func (r *mutationResolver) CreateAccessToken(ctx context.Context, name string, scopes []model.TokenScope, expiresAt *time.Time) (*model.CreatedPersonalAccessToken, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Otherwise a leaked token could be used to mint more tokens that outlive it
+	if _, err := auth.GetAccessTokenFromContext(ctx); err == nil {
+		return nil, apperr.Forbidden("access tokens cannot create other access tokens")
+	}
+
+	tokenScopes := make([]string, len(scopes))
+	for i, scope := range scopes {
+		tokenScopes[i] = strings.ToLower(scope.String())
+	}
+
+	plainToken, token, err := r.AccessTokenService.CreateToken(ctx, currentUser, name, tokenScopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatedPersonalAccessToken{
+		Token:               plainToken,
+		PersonalAccessToken: accessTokenToModel(token),
+	}, nil
+}
+
+// RevokeAccessToken is the resolver for the revokeAccessToken field.
+func (r *mutationResolver) RevokeAccessToken(ctx context.Context, id string) (bool, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tokenID, err := globalid.Decode(globalid.PersonalAccessToken, id)
+	if err != nil {
+		return false, err
+	}
+
+	return r.AccessTokenService.RevokeToken(ctx, currentUser.ID, tokenID)
+}
+
+// DeleteMyAccount is the resolver for the deleteMyAccount field.
+func (r *mutationResolver) DeleteMyAccount(ctx context.Context, confirmEmail string) (bool, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, err := auth.GetAccessTokenFromContext(ctx); err == nil {
+		return false, apperr.Forbidden("accounts can only be deleted when signed in through the browser")
+	}
+
+	if err := r.UserService.DeleteAccount(ctx, currentUser, confirmEmail); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RequestDataExport is the resolver for the requestDataExport field.
+func (r *mutationResolver) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, export, err := r.DataExportService.RequestArchive(ctx, currentUser)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DataExport{
+		URL:       "/export/archive?token=" + url.QueryEscape(token),
+		ExpiresAt: export.ExpiresAt,
+	}, nil
+}
+
+// UnlinkIdentity is the resolver for the unlinkIdentity field.
+func (r *mutationResolver) UnlinkIdentity(ctx context.Context, id string) (bool, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, err := auth.GetAccessTokenFromContext(ctx); err == nil {
+		return false, apperr.Forbidden("providers can only be unlinked when signed in through the browser")
+	}
+
+	identityID, err := globalid.Decode(globalid.LinkedIdentity, id)
+	if err != nil {
+		return false, err
+	}
+
+	return r.IdentityService.Unlink(ctx, currentUser, identityID)
+}
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, id string, role string) (*model.User, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := globalid.Decode(globalid.User, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserService.ChangeRole(ctx, currentUser, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return userToModel(r.Policy, user, currentUser), nil
+}
+
+// SuspendUser is the resolver for the suspendUser field.
+func (r *mutationResolver) SuspendUser(ctx context.Context, id string) (*model.User, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := globalid.Decode(globalid.User, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserService.SuspendUser(ctx, currentUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userToModel(r.Policy, user, currentUser), nil
+}
+
+// ReactivateUser is the resolver for the reactivateUser field.
+func (r *mutationResolver) ReactivateUser(ctx context.Context, id string) (*model.User, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := globalid.Decode(globalid.User, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserService.ReactivateUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userToModel(r.Policy, user, currentUser), nil
+}
+
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userID, err := globalid.Decode(globalid.User, id)
+	if err != nil {
+		return false, err
+	}
+
+	return r.UserService.DeleteUser(ctx, currentUser, userID)
+}
+
+// Node is the resolver for the node field.
+func (r *queryResolver) Node(ctx context.Context, id string) (model.Node, error) {
+	return r.node(ctx, id)
+}
+
+// Nodes is the resolver for the nodes field.
+func (r *queryResolver) Nodes(ctx context.Context, ids []string) ([]model.Node, error) {
+	if len(ids) > maxNodes {
+		return nil, apperr.Validation(fmt.Sprintf("at most %d IDs can be looked up at once", maxNodes))
+	}
+
+	// Loaded concurrently so the loaders fetch movies and users in one batch each
+	nodes := make([]model.Node, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			nodes[i], errs[i] = r.node(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
 // Movie is the resolver for the movie field.
 func (r *queryResolver) Movie(ctx context.Context, id string) (*model.Movie, error) {
-	movieID, err := uuid.Parse(id)
+	movieID, err := globalid.Decode(globalid.Movie, id)
 	if err != nil {
-		return nil, errors.New("invalid movie ID")
+		return nil, err
 	}
 
 	// Fetch the movie
@@ -92,17 +380,9 @@ func (r *queryResolver) Movie(ctx context.Context, id string) (*model.Movie, err
 		return nil, err
 	}
 	if movie == nil {
-		return nil, errors.New("movie not found")
-	}
-	return &model.Movie{
-		ID:    movieID.String(),
-		Title: movie.Title,
-		Genre: movie.Genre,
-		Year:  movie.Year,
-		Wiki:  movie.Wiki,
-		Plot:  movie.Plot,
-		Cast:  movie.Cast,
-	}, nil
+		return nil, apperr.NotFound("movie not found")
+	}
+	return movieToModel(movie), nil
 }
 
 // MovieByTitle is the resolver for the movieByTitle field.
@@ -113,17 +393,9 @@ func (r *queryResolver) MovieByTitle(ctx context.Context, title string) (*model.
 		return nil, err
 	}
 	if movie == nil {
-		return nil, errors.New("movie not found")
-	}
-	return &model.Movie{
-		ID:    movie.ID.String(),
-		Title: movie.Title,
-		Genre: movie.Genre,
-		Year:  movie.Year,
-		Wiki:  movie.Wiki,
-		Plot:  movie.Plot,
-		Cast:  movie.Cast,
-	}, nil
+		return nil, apperr.NotFound("movie not found")
+	}
+	return movieToModel(movie), nil
 }
 
 // Movies is the resolver for the movies field.
@@ -131,38 +403,14 @@ func (r *queryResolver) Movies(ctx context.Context, page int, pageSize int) (*mo
 	if page < 1 {
 		page = 1
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10 // Default page size
-	}
+	pageSize = clampPageSize(pageSize)
 
 	moviePage, err := r.MovieService.GetMovies(ctx, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	edges := make([]*model.MovieEdge, len(moviePage.Movies))
-	for i, movie := range moviePage.Movies {
-		edges[i] = &model.MovieEdge{
-			Node: &model.Movie{
-				ID:    movie.ID.String(),
-				Title: movie.Title,
-				Genre: movie.Genre,
-				Year:  movie.Year,
-				Wiki:  movie.Wiki,
-				Plot:  movie.Plot,
-				Cast:  movie.Cast,
-			},
-		}
-	}
-
-	return &model.MovieConnection{
-		Edges: edges,
-		PageInfo: &model.PageInfo{
-			HasNextPage:     moviePage.HasNextPage,
-			HasPreviousPage: moviePage.HasPreviousPage,
-		},
-		TotalCount: moviePage.TotalCount,
-	}, nil
+	return movieConnectionToModel(moviePage), nil
 }
 
 // SearchMovies is the resolver for the searchMovies field.
@@ -170,42 +418,169 @@ func (r *queryResolver) SearchMovies(ctx context.Context, query string, page int
 	if page < 1 {
 		page = 1
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10 // Default page size
-	}
+	pageSize = clampPageSize(pageSize)
 
 	moviePage, err := r.MovieService.SearchMovies(ctx, query, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	edges := make([]*model.MovieEdge, len(moviePage.Movies))
-	for i, movie := range moviePage.Movies {
-		edges[i] = &model.MovieEdge{
-			Node: &model.Movie{
-				ID:    movie.ID.String(),
-				Title: movie.Title,
-				Genre: movie.Genre,
-				Year:  movie.Year,
-				Wiki:  movie.Wiki,
-				Plot:  movie.Plot,
-				Cast:  movie.Cast,
-			},
+	return movieConnectionToModel(moviePage), nil
+}
+
+// Recommendations is the resolver for the recommendations field.
+func (r *queryResolver) Recommendations(ctx context.Context, page int, pageSize int) (*model.MovieConnection, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	pageSize = clampPageSize(pageSize)
+
+	moviePage, err := r.RecommendationService.GetSimilarMovies(ctx, currentUser.Taste, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return movieConnectionToModel(moviePage), nil
+}
+
+// Ratings is the resolver for the ratings field.
+func (r *queryResolver) Ratings(ctx context.Context, userID string) ([]*model.Rating, error) {
+	id, err := globalid.Decode(globalid.User, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := r.RatingService.GetRatingsByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The movie and user of each rating are batched by the loaders
+	result := make([]*model.Rating, len(ratings))
+	for i, rating := range ratings {
+		result[i] = ratingToModel(rating)
+	}
+	return result, nil
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := globalid.Decode(globalid.User, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	return userToModel(r.Policy, user, currentUser), nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user in the context may be stale, so load the current profile
+	user, err := r.UserService.GetUserByID(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	return userToModel(r.Policy, user, currentUser), nil
+}
+
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*model.Session, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Requests made with an access token have no current session
+	currentSession, _ := auth.GetSessionFromContext(ctx)
+
+	sessions, err := r.SessionService.ListSessions(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = &model.Session{
+			ID:         globalid.Encode(globalid.Session, session.ID),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  r.SessionService.SessionExpiry(session),
+			Current:    currentSession != nil && session.ID == currentSession.ID,
 		}
 	}
+	return result, nil
+}
 
-	return &model.MovieConnection{
-		Edges: edges,
-		PageInfo: &model.PageInfo{
-			HasNextPage:     moviePage.HasNextPage,
-			HasPreviousPage: moviePage.HasPreviousPage,
-		},
-		TotalCount: moviePage.TotalCount,
-	}, nil
+// MyAccessTokens is the resolver for the myAccessTokens field.
+func (r *queryResolver) MyAccessTokens(ctx context.Context) ([]*model.PersonalAccessToken, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := r.AccessTokenService.ListTokens(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.PersonalAccessToken, len(tokens))
+	for i, token := range tokens {
+		result[i] = accessTokenToModel(token)
+	}
+	return result, nil
 }
 
-// Recommendations is the resolver for the recommendations field.
-func (r *queryResolver) Recommendations(ctx context.Context, page int, pageSize int) (*model.MovieConnection, error) {
+// MyIdentities is the resolver for the myIdentities field.
+func (r *queryResolver) MyIdentities(ctx context.Context) ([]*model.LinkedIdentity, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := r.IdentityService.ListIdentities(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.LinkedIdentity, len(identities))
+	for i, identity := range identities {
+		result[i] = &model.LinkedIdentity{
+			ID:        globalid.Encode(globalid.LinkedIdentity, identity.ID),
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, search *string, page int, pageSize int) (*model.UserConnection, error) {
 	currentUser, err := auth.GetUserFromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -214,48 +589,200 @@ func (r *queryResolver) Recommendations(ctx context.Context, page int, pageSize
 	if page < 1 {
 		page = 1
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10 // Default page size
+	pageSize = clampPageSize(pageSize)
+
+	searchTerm := ""
+	if search != nil {
+		searchTerm = *search
 	}
 
-	moviePage, err := r.RecommendationService.GetSimilarMovies(ctx, currentUser.Taste, page, pageSize)
+	userPage, err := r.UserService.SearchUsers(ctx, searchTerm, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	edges := make([]*model.MovieEdge, len(moviePage.Movies))
-	for i, movie := range moviePage.Movies {
-		edges[i] = &model.MovieEdge{
-			Node: &model.Movie{
-				ID:    movie.ID.String(),
-				Title: movie.Title,
-				Genre: movie.Genre,
-				Year:  movie.Year,
-				Wiki:  movie.Wiki,
-				Plot:  movie.Plot,
-				Cast:  movie.Cast,
-			},
+	edges := make([]*model.UserEdge, len(userPage.Users))
+	for i, user := range userPage.Users {
+		edges[i] = &model.UserEdge{
+			Node: userToModel(r.Policy, user, currentUser),
 		}
 	}
 
-	return &model.MovieConnection{
+	return &model.UserConnection{
 		Edges: edges,
 		PageInfo: &model.PageInfo{
-			HasNextPage:     moviePage.HasNextPage,
-			HasPreviousPage: moviePage.HasPreviousPage,
+			HasNextPage:     userPage.HasNextPage,
+			HasPreviousPage: userPage.HasPreviousPage,
 		},
-		TotalCount: moviePage.TotalCount,
+		TotalCount: userPage.TotalCount,
 	}, nil
 }
 
-// Ratings is the resolver for the ratings field.
-func (r *queryResolver) Ratings(ctx context.Context, userID string) ([]*model.Rating, error) {
-	panic(fmt.Errorf("not implemented: Ratings - ratings"))
+// Roles is the resolver for the roles field.
+func (r *queryResolver) Roles(ctx context.Context) ([]string, error) {
+	roles := r.Policy.Roles()
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return names, nil
 }
 
 // User is the resolver for the user field.
-func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
-	panic(fmt.Errorf("not implemented: User - user"))
+func (r *ratingResolver) User(ctx context.Context, obj *model.Rating) (*model.User, error) {
+	user, err := r.loaders(ctx).Users.Load(ctx, obj.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	// Anonymous viewers only see public fields
+	viewer, _ := auth.GetUserFromContext(ctx)
+	return userToModel(r.Policy, user, viewer), nil
+}
+
+// Movie is the resolver for the movie field.
+func (r *ratingResolver) Movie(ctx context.Context, obj *model.Rating) (*model.Movie, error) {
+	movie, err := r.loaders(ctx).Movies.Load(ctx, obj.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, apperr.NotFound("movie not found")
+	}
+	return movieToModel(movie), nil
+}
+
+// RecommendationsUpdated is the resolver for the recommendationsUpdated field.
+func (r *subscriptionResolver) RecommendationsUpdated(ctx context.Context, pageSize int) (<-chan *model.MovieConnection, error) {
+	currentUser, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageSize = clampPageSize(pageSize)
+
+	changes, err := r.LiveService.SubscribeRecommendations(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	connections := make(chan *model.MovieConnection)
+	go func() {
+		defer close(connections)
+		for range changes {
+			// The user in the context still has the taste from when the subscription started
+			user, err := r.UserService.GetUserByID(ctx, currentUser.ID)
+			if err != nil {
+				log.Printf("Failed to get user for recommendations update: %v", err)
+				return
+			}
+			// Deleted and suspended users stop getting updates
+			if user == nil || user.SuspendedAt != nil {
+				return
+			}
+
+			moviePage, err := r.RecommendationService.GetSimilarMovies(ctx, user.Taste, 1, pageSize)
+			if err != nil {
+				log.Printf("Failed to get recommendations update: %v", err)
+				return
+			}
+			select {
+			case connections <- movieConnectionToModel(moviePage):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return connections, nil
+}
+
+// MovieRatingStatsChanged is the resolver for the movieRatingStatsChanged field.
+func (r *subscriptionResolver) MovieRatingStatsChanged(ctx context.Context, movieID string) (<-chan *model.MovieRatingStats, error) {
+	movieUUID, err := globalid.Decode(globalid.Movie, movieID)
+	if err != nil {
+		return nil, err
+	}
+	movie, err := r.MovieService.GetMovieByID(ctx, movieUUID)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		return nil, apperr.NotFound("movie not found")
+	}
+
+	summaries, err := r.LiveService.SubscribeMovieRatingSummary(ctx, movieUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(chan *model.MovieRatingStats)
+	go func() {
+		defer close(stats)
+		for summary := range summaries {
+			select {
+			case stats <- &model.MovieRatingStats{
+				MovieID:      globalid.Encode(globalid.Movie, movie.ID),
+				RatingCount:  summary.Count,
+				AverageScore: summary.AverageScore,
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return stats, nil
+}
+
+// RatingCount is the resolver for the ratingCount field.
+func (r *userResolver) RatingCount(ctx context.Context, obj *model.User) (int, error) {
+	userID, err := globalid.Decode(globalid.User, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	summary, err := r.loaders(ctx).RatingSummaries.Load(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Count, nil
+}
+
+// AverageScore is the resolver for the averageScore field.
+func (r *userResolver) AverageScore(ctx context.Context, obj *model.User) (float64, error) {
+	userID, err := globalid.Decode(globalid.User, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	summary, err := r.loaders(ctx).RatingSummaries.Load(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return summary.AverageScore, nil
+}
+
+// TopGenres is the resolver for the topGenres field.
+func (r *userResolver) TopGenres(ctx context.Context, obj *model.User) ([]*model.GenreSummary, error) {
+	userID, err := globalid.Decode(globalid.User, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	genres, err := r.UserService.GetTopGenres(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	topGenres := make([]*model.GenreSummary, len(genres))
+	for i, genre := range genres {
+		topGenres[i] = &model.GenreSummary{
+			Genre:        genre.Genre,
+			RatingCount:  genre.Count,
+			AverageScore: genre.AverageScore,
+		}
+	}
+	return topGenres, nil
 }
 
 // Mutation returns MutationResolver implementation.
@@ -264,5 +791,17 @@ func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Rating returns RatingResolver implementation.
+func (r *Resolver) Rating() RatingResolver { return &ratingResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type ratingResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
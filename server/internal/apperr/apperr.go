@@ -0,0 +1,65 @@
+// Package apperr gives errors a code clients can act on instead of matching messages. GraphQL responses
+// report it in extensions.code. Errors without a code are internal, clients never see their message.
+package apperr
+
+import "errors"
+
+type Code string
+
+const (
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeValidation      Code = "VALIDATION_FAILED"
+	CodeConflict        Code = "CONFLICT"
+	CodeInternal        Code = "INTERNAL"
+)
+
+// Error is an error whose message is meant for the client
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// NotFound is for something that doesn't exist, or that the user may not know exists
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+// Unauthenticated is for requests that need a signed in user but have none, or invalid credentials
+func Unauthenticated(message string) *Error {
+	return &Error{Code: CodeUnauthenticated, Message: message}
+}
+
+// Forbidden is for signed in users who may not do what they asked
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+// Validation is for input the user has to correct
+func Validation(message string) *Error {
+	return &Error{Code: CodeValidation, Message: message}
+}
+
+// Conflict is for requests that clash with the current state, such as a provider account that is already linked
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+// CodeOf returns the code of err, or of the first error it wraps that has one. Errors of other packages
+// can have a code too by implementing ErrorCode. Errors without a code are CodeInternal.
+func CodeOf(err error) Code {
+	var coded interface{ ErrorCode() Code }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return CodeInternal
+}
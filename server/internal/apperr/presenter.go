@@ -0,0 +1,58 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const internalMessage = "internal server error"
+
+// Presenter is a gqlgen error presenter that puts the code of an error in extensions.code.
+// Internal errors are logged with a correlation ID, the client only gets the ID to report.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if _, ok := gqlErr.Extensions["code"]; ok {
+		return gqlErr
+	}
+
+	if code := CodeOf(err); code != CodeInternal {
+		setCode(gqlErr, code)
+		return gqlErr
+	}
+	// gqlgen's own errors, e.g. for an argument of the wrong type, are about the request and meant for the client
+	var requestErr *gqlerror.Error
+	if errors.As(err, &requestErr) {
+		return gqlErr
+	}
+
+	correlationID := uuid.NewString()
+	log.Printf("Internal error %s at %s: %v", correlationID, gqlErr.Path, err)
+	return &gqlerror.Error{
+		Message:   internalMessage,
+		Path:      gqlErr.Path,
+		Locations: gqlErr.Locations,
+		Extensions: map[string]interface{}{
+			"code":          CodeInternal,
+			"correlationId": correlationID,
+		},
+	}
+}
+
+// Recover turns a panic in a resolver into an internal error, which Presenter logs with its stack
+func Recover(ctx context.Context, recovered interface{}) error {
+	return fmt.Errorf("panic: %v\n%s", recovered, debug.Stack())
+}
+
+func setCode(gqlErr *gqlerror.Error, code Code) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]interface{})
+	}
+	gqlErr.Extensions["code"] = code
+}
@@ -0,0 +1,98 @@
+package apperr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type quotaError struct{}
+
+func (quotaError) Error() string   { return "quota exceeded" }
+func (quotaError) ErrorCode() Code { return CodeConflict }
+
+func TestPresenter(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		err         error
+		wantMessage string
+		wantCode    interface{}
+	}{
+		{
+			name:        "Typed Error",
+			err:         NotFound("movie not found"),
+			wantMessage: "movie not found",
+			wantCode:    CodeNotFound,
+		},
+		{
+			name:        "Wrapped Typed Error",
+			err:         fmt.Errorf("deleting rating: %w", Forbidden("not your rating")),
+			wantMessage: "deleting rating: not your rating",
+			wantCode:    CodeForbidden,
+		},
+		{
+			name:        "Error Of Another Package With A Code",
+			err:         quotaError{},
+			wantMessage: "quota exceeded",
+			wantCode:    CodeConflict,
+		},
+		{
+			name:        "GraphQL Error With A Code",
+			err:         &gqlerror.Error{Message: "query is too deep", Extensions: map[string]interface{}{"code": "QUERY_TOO_DEEP"}},
+			wantMessage: "query is too deep",
+			wantCode:    "QUERY_TOO_DEEP",
+		},
+		{
+			name:        "GraphQL Error About The Request",
+			err:         gqlerror.Errorf("cannot parse time"),
+			wantMessage: "cannot parse time",
+			wantCode:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Presenter(ctx, tt.err)
+			assert.Equal(t, tt.wantMessage, got.Message)
+			assert.Equal(t, tt.wantCode, got.Extensions["code"])
+		})
+	}
+}
+
+func TestPresenter_HidesInternalErrors(t *testing.T) {
+	var logged bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logged)
+
+	for _, err := range []error{
+		errors.New("pq: connection refused"),
+		Recover(context.Background(), "nil pointer dereference"),
+	} {
+		got := Presenter(context.Background(), err)
+		assert.Equal(t, "internal server error", got.Message)
+		assert.Equal(t, CodeInternal, got.Extensions["code"])
+
+		correlationID, ok := got.Extensions["correlationId"].(string)
+		require.True(t, ok)
+		assert.Contains(t, logged.String(), correlationID)
+		assert.NotContains(t, got.Error(), err.Error())
+	}
+	assert.Contains(t, logged.String(), "pq: connection refused")
+	assert.Contains(t, logged.String(), "panic: nil pointer dereference")
+}
+
+func TestCodeOf(t *testing.T) {
+	assert.Equal(t, CodeValidation, CodeOf(Validation("score must be between 0 and 5")))
+	assert.Equal(t, CodeUnauthenticated, CodeOf(fmt.Errorf("signing in: %w", Unauthenticated("invalid session"))))
+	assert.Equal(t, CodeInternal, CodeOf(errors.New("disk full")))
+	assert.Equal(t, CodeInternal, CodeOf(nil))
+}
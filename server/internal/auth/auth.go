@@ -2,19 +2,19 @@ package auth
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/models"
 )
 
+// ErrUnauthenticated is returned for anonymous requests to something that needs a user
+var ErrUnauthenticated = apperr.Unauthenticated("authentication required")
+
 // GetUserFromContext gets the set user from context
 func GetUserFromContext(ctx context.Context) (*models.User, error) {
 	user := ctx.Value("user")
@@ -25,59 +25,62 @@ func GetUserFromContext(ctx context.Context) (*models.User, error) {
 	return user.(*models.User), nil
 }
 
-// Generates a hexadecimal string of random bytes with a specified length
-func randomBytesInHex(count int) (string, error) {
-	buf := make([]byte, count)
-	_, err := io.ReadFull(rand.Reader, buf)
-	if err != nil {
-		return "", fmt.Errorf("could not generate %d random bytes: %v", count, err)
-	}
-
-	return hex.EncodeToString(buf), nil
+// WithAuthError records why the credentials of a request were rejected
+func WithAuthError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, "auth_error", err)
 }
 
-// Helper function to encrypt a token
-func EncryptToken(token string) (string, error) {
-	key := []byte(os.Getenv("ENCRYPTION_KEY"))
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+// RequireUser returns the signed in user, or why there is none
+func RequireUser(ctx context.Context) (*models.User, error) {
+	if user, ok := ctx.Value("user").(*models.User); ok {
+		return user, nil
 	}
-
-	plaintext := []byte(token)
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", err
+	if err, ok := ctx.Value("auth_error").(error); ok {
+		return nil, err
 	}
+	return nil, ErrUnauthenticated
+}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
+// GetSessionFromContext gets the session the request was authenticated with
+func GetSessionFromContext(ctx context.Context) (*models.Session, error) {
+	session := ctx.Value("session")
 
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
+	if session == nil {
+		return nil, errors.New("session not found")
+	}
+	return session.(*models.Session), nil
 }
 
-// Helper function to decrypt a token
-func decryptToken(encryptedToken string) (string, error) {
-	key := []byte(os.Getenv("ENCRYPTION_KEY"))
-	ciphertext, err := base64.URLEncoding.DecodeString(encryptedToken)
-	if err != nil {
-		return "", err
+// GetAccessTokenFromContext gets the personal access token the request was authenticated with, if any
+func GetAccessTokenFromContext(ctx context.Context) (*models.PersonalAccessToken, error) {
+	token := ctx.Value("access_token")
+
+	if token == nil {
+		return nil, errors.New("access token not found")
 	}
+	return token.(*models.PersonalAccessToken), nil
+}
 
-	block, err := aes.NewCipher(key)
+// HasScope reports whether the request may act within scope. Browser sessions can do anything
+// the user can, personal access tokens only what they were granted.
+func HasScope(ctx context.Context, scope string) bool {
+	token, err := GetAccessTokenFromContext(ctx)
 	if err != nil {
-		return "", err
+		return true
 	}
+	return token.HasScope(scope)
+}
 
-	if len(ciphertext) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+// Generates a hexadecimal string of random bytes with a specified length
+func randomBytesInHex(count int) (string, error) {
+	if count < 0 {
+		return "", fmt.Errorf("could not generate %d random bytes: count cannot be negative", count)
+	}
+	buf := make([]byte, count)
+	_, err := io.ReadFull(rand.Reader, buf)
+	if err != nil {
+		return "", fmt.Errorf("could not generate %d random bytes: %v", count, err)
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
 
-	return string(ciphertext), nil
+	return hex.EncodeToString(buf), nil
 }
@@ -2,7 +2,7 @@ package auth
 
 import (
 	"context"
-	"os"
+	"errors"
 	"testing"
 
 	"github.com/Azanul/Next-Watch/internal/models"
@@ -32,6 +32,49 @@ func TestGetUserFromContext(t *testing.T) {
 	})
 }
 
+func TestRequireUser(t *testing.T) {
+	t.Run("Signed in", func(t *testing.T) {
+		expectedUser := &models.User{ID: uuid.New()}
+		ctx := context.WithValue(context.Background(), "user", expectedUser)
+
+		user, err := RequireUser(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedUser, user)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		_, err := RequireUser(context.Background())
+
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("Rejected credentials", func(t *testing.T) {
+		ctx := WithAuthError(context.Background(), errors.New("Session expired"))
+
+		_, err := RequireUser(ctx)
+
+		assert.EqualError(t, err, "Session expired")
+	})
+}
+
+func TestHasScope(t *testing.T) {
+	t.Run("Session can do anything", func(t *testing.T) {
+		ctx := context.Background()
+
+		assert.True(t, HasScope(ctx, models.ScopeRead))
+		assert.True(t, HasScope(ctx, models.ScopeWrite))
+	})
+
+	t.Run("Access token is limited to its scopes", func(t *testing.T) {
+		token := &models.PersonalAccessToken{ID: uuid.New(), Scopes: []string{models.ScopeRead}}
+		ctx := context.WithValue(context.Background(), "access_token", token)
+
+		assert.True(t, HasScope(ctx, models.ScopeRead))
+		assert.False(t, HasScope(ctx, models.ScopeWrite))
+	})
+}
+
 func TestRandomBytesInHex(t *testing.T) {
 	t.Run("Generate random bytes", func(t *testing.T) {
 		count := 16
@@ -51,34 +94,3 @@ func TestRandomBytesInHex(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
-
-func TestEncryptDecryptToken(t *testing.T) {
-	t.Run("Encrypt and decrypt token", func(t *testing.T) {
-		os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef") // 32-byte key
-		originalToken := "test-token"
-
-		encryptedToken, err := EncryptToken(originalToken)
-		assert.NoError(t, err)
-		assert.NotEqual(t, originalToken, encryptedToken)
-
-		decryptedToken, err := decryptToken(encryptedToken)
-		assert.NoError(t, err)
-		assert.Equal(t, originalToken, decryptedToken)
-	})
-
-	t.Run("Decrypt invalid token", func(t *testing.T) {
-		os.Setenv("ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
-		invalidToken := "invalid-token"
-
-		_, err := decryptToken(invalidToken)
-		assert.Error(t, err)
-	})
-
-	t.Run("Encrypt with invalid key", func(t *testing.T) {
-		os.Setenv("ENCRYPTION_KEY", "invalid-key")
-		token := "test-token"
-
-		_, err := EncryptToken(token)
-		assert.Error(t, err)
-	})
-}
@@ -2,13 +2,7 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/base64"
 	"errors"
-	"fmt"
-	"io"
-	"os"
-	"sync"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -16,93 +10,73 @@ import (
 	"google.golang.org/api/option"
 )
 
-type GoogleClaims struct {
-	Email string `json:"email"`
-	Name  string `json:"name"`
-}
-
 type GoogleAuthClient struct {
 	*oauth2.Config
-	codeVerifiers map[string]string
-	mu            sync.Mutex
+	states        StateStore
+	offlineAccess bool
+}
+
+// Checking if GoogleAuthClient implements IdentityProvider during compile time
+var _ IdentityProvider = (*GoogleAuthClient)(nil)
+
+// GoogleConfig is the OAuth client the app is registered as with Google
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// OfflineAccess asks Google for a refresh token, which it only hands out when the user first consents
+	OfflineAccess bool
 }
 
-func NewGoogleAuthClient() *GoogleAuthClient {
+func NewGoogleAuthClient(config GoogleConfig, states StateStore) *GoogleAuthClient {
 	var googleOauthConfig = &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectURL,
 		Scopes:       []string{"email", "profile"},
 		Endpoint:     google.Endpoint,
 	}
 
 	return &GoogleAuthClient{
-		googleOauthConfig,
-		make(map[string]string),
-		sync.Mutex{},
+		Config:        googleOauthConfig,
+		states:        states,
+		offlineAccess: config.OfflineAccess,
 	}
 }
 
-func (g *GoogleAuthClient) AuthorizationURL() (string, error) {
-	codeVerifier, verifierErr := randomBytesInHex(32)
-	if verifierErr != nil {
-		return "", fmt.Errorf("could not create a code verifier: %v", verifierErr)
-	}
-
-	sha2 := sha256.New()
-	io.WriteString(sha2, codeVerifier)
-	codeChallenge := base64.RawURLEncoding.EncodeToString(sha2.Sum(nil))
+func (g *GoogleAuthClient) Name() string {
+	return "google"
+}
 
-	state, err := randomBytesInHex(24)
-	if err != nil {
-		return "", fmt.Errorf("could not generate random state: %v", err)
+func (g *GoogleAuthClient) AuthorizationURL(ctx context.Context, linkUserID string) (string, error) {
+	if g.offlineAccess {
+		return startSignIn(ctx, g.states, g.Name(), g.Config, false, linkUserID, oauth2.AccessTypeOffline)
 	}
-
-	g.mu.Lock()
-	g.codeVerifiers[state] = codeVerifier
-	g.mu.Unlock()
-
-	return g.AuthCodeURL(
-		state,
-		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
-	), nil
+	return startSignIn(ctx, g.states, g.Name(), g.Config, false, linkUserID)
 }
 
-func (g *GoogleAuthClient) Callback(code string, state string) (*oauth2.Token, error) {
-	g.mu.Lock()
-	codeVerifier, exists := g.codeVerifiers[state]
-	if !exists {
-		g.mu.Unlock()
-		return nil, errors.New("no matching code verifier found for state")
-	}
-	delete(g.codeVerifiers, state)
-	g.mu.Unlock()
-
-	token, err := g.Exchange(
-		context.Background(),
-		code,
-		oauth2.SetAuthURLParam("code_verifier", codeVerifier),
-	)
+// Callback exchanges the authorization code, failing with ErrInvalidState if the state is unknown or expired
+func (g *GoogleAuthClient) Callback(ctx context.Context, code string, state string) (*Identity, *SignInState, error) {
+	token, signIn, err := finishSignIn(ctx, g.states, g.Name(), g.Config, code, state)
 	if err != nil {
-		return nil, fmt.Errorf("error while exchanging token: %v", err)
+		return nil, nil, err
 	}
 
-	return token, nil
-}
-
-func (g *GoogleAuthClient) ValidateToken(token string) (*GoogleClaims, error) {
-	tokenString, err := decryptToken(token)
+	identity, err := g.GetUserInfo(ctx, token.AccessToken)
 	if err != nil {
-		return nil, errors.New("failed to decrypt token")
+		return nil, nil, err
 	}
+	identity.RefreshToken = token.RefreshToken
+	return identity, signIn, nil
+}
 
-	return g.GetUserInfo(tokenString)
+func (g *GoogleAuthClient) TokenSource(ctx context.Context, refreshToken string) oauth2.TokenSource {
+	return refreshTokenSource(ctx, g.Config, refreshToken)
 }
 
-func (g *GoogleAuthClient) GetUserInfo(accessToken string) (*GoogleClaims, error) {
-	client := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}))
-	oauth2Service, err := oauth2v2.NewService(context.Background(), option.WithHTTPClient(client))
+func (g *GoogleAuthClient) GetUserInfo(ctx context.Context, accessToken string) (*Identity, error) {
+	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}))
+	oauth2Service, err := oauth2v2.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, errors.New("failed to create OAuth2 service")
 	}
@@ -112,10 +86,14 @@ func (g *GoogleAuthClient) GetUserInfo(accessToken string) (*GoogleClaims, error
 		return nil, errors.New("failed to get user info")
 	}
 
-	claims := &GoogleClaims{
-		Email: userInfo.Email,
-		Name:  userInfo.Name,
+	identity := &Identity{
+		Provider:      g.Name(),
+		Subject:       userInfo.Id,
+		Email:         userInfo.Email,
+		EmailVerified: userInfo.VerifiedEmail != nil && *userInfo.VerifiedEmail,
+		Name:          userInfo.Name,
+		Picture:       userInfo.Picture,
 	}
 
-	return claims, nil
+	return identity, nil
 }
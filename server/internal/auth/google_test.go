@@ -1,15 +1,17 @@
 package auth
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGoogleAuthClient_AuthorizationURL(t *testing.T) {
-	client := NewGoogleAuthClient()
+	states := NewMemoryStateStore()
+	client := NewGoogleAuthClient(GoogleConfig{}, states)
 
-	url, err := client.AuthorizationURL()
+	url, err := client.AuthorizationURL(context.Background(), "")
 
 	assert.NoError(t, err)
 	assert.Contains(t, url, "accounts.google.com/o/oauth2/auth")
@@ -17,5 +19,20 @@ func TestGoogleAuthClient_AuthorizationURL(t *testing.T) {
 	assert.Contains(t, url, "code_challenge=")
 	assert.Contains(t, url, "state=")
 
-	assert.Len(t, client.codeVerifiers, 1)
+	assert.NotContains(t, url, "access_type=offline")
+
+	assert.Len(t, states.states, 1)
+
+	offline := NewGoogleAuthClient(GoogleConfig{OfflineAccess: true}, states)
+	url, err = offline.AuthorizationURL(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Contains(t, url, "access_type=offline")
+}
+
+func TestGoogleAuthClient_Callback_InvalidState(t *testing.T) {
+	client := NewGoogleAuthClient(GoogleConfig{}, NewMemoryStateStore())
+
+	_, _, err := client.Callback(context.Background(), "code", "unknown")
+
+	assert.ErrorIs(t, err, ErrInvalidState)
 }
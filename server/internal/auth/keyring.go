@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidCiphertext is returned for ciphertexts that were tampered with, truncated or sealed with an unknown key
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Keyring encrypts tokens with AES-GCM. Every ciphertext starts with the ID of the key that sealed it,
+// so keys can be rotated: the primary key encrypts and every key in the ring decrypts.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring builds a keyring from raw AES keys, which must be 16, 24 or 32 bytes long
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary encryption key %q is not in the keyring", primary)
+	}
+
+	k := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid encryption key ID %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q must be 16, 24 or 32 bytes long, got %d", id, len(key))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeyring reads keys written as "id:base64key,id:base64key". The first key is the primary,
+// the others are only kept to decrypt what they sealed before a rotation.
+func ParseKeyring(spec string) (*Keyring, error) {
+	var primary string
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("encryption key %q is not written as id:key", entry)
+		}
+		if _, seen := keys[id]; seen {
+			return nil, fmt.Errorf("duplicate encryption key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		if primary == "" {
+			primary = id
+		}
+		keys[id] = key
+	}
+	return NewKeyring(primary, keys)
+}
+
+// LoadKeyring builds the keyring configured with ENCRYPTION_KEYS, see ParseKeyring. A single raw
+// ENCRYPTION_KEY is still accepted as legacyKey for deployments that predate rotation.
+func LoadKeyring(spec, legacyKey string) (*Keyring, error) {
+	if spec != "" {
+		return ParseKeyring(spec)
+	}
+	if legacyKey != "" {
+		return NewKeyring("default", map[string][]byte{"default": []byte(legacyKey)})
+	}
+	return nil, errors.New("ENCRYPTION_KEYS is not set")
+}
+
+// Encrypt seals a token with the primary key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.keys[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	// The key ID is authenticated too, so a ciphertext can't be moved to another key
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.primary))
+	return k.primary + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a token sealed by any key in the ring
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ".")
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
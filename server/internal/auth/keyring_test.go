@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = []byte("0123456789abcdef0123456789abcdef")
+	newKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	require.NoError(t, err)
+
+	encrypted, err := keyring.Encrypt("test-token")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "v1."))
+	assert.NotContains(t, encrypted, "test-token")
+
+	decrypted, err := keyring.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-token", decrypted)
+
+	again, err := keyring.Encrypt("test-token")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, again, "nonces must not repeat")
+}
+
+func TestKeyring_Decrypt_Invalid(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	require.NoError(t, err)
+	encrypted, err := keyring.Encrypt("test-token")
+	require.NoError(t, err)
+
+	// Flip a bit of the last byte, which belongs to the authentication tag
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encrypted, "v1."))
+	require.NoError(t, err)
+	sealed[len(sealed)-1] ^= 1
+	tampered := "v1." + base64.RawURLEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		ciphertext string
+	}{
+		{name: "Tampered", ciphertext: tampered},
+		{name: "Unknown Key", ciphertext: "v2" + strings.TrimPrefix(encrypted, "v1")},
+		{name: "No Key ID", ciphertext: "invalid-token"},
+		{name: "Not Base64", ciphertext: "v1.!!!"},
+		{name: "Too Short", ciphertext: "v1.AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keyring.Decrypt(tt.ciphertext)
+			assert.ErrorIs(t, err, ErrInvalidCiphertext)
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	before, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	require.NoError(t, err)
+	encrypted, err := before.Encrypt("test-token")
+	require.NoError(t, err)
+
+	after, err := ParseKeyring("v2:" + base64.StdEncoding.EncodeToString(newKey) + ", v1:" + base64.StdEncoding.EncodeToString(oldKey))
+	require.NoError(t, err)
+
+	decrypted, err := after.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-token", decrypted)
+
+	reencrypted, err := after.Encrypt(decrypted)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(reencrypted, "v2."))
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(oldKey)
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "Short Key", spec: "v1:" + base64.StdEncoding.EncodeToString([]byte("invalid-key"))},
+		{name: "Missing ID", spec: key},
+		{name: "Invalid ID", spec: "v.1:" + key},
+		{name: "Duplicate ID", spec: "v1:" + key + ",v1:" + key},
+		{name: "Not Base64", spec: "v1:not base64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeyring(tt.spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	_, err := LoadKeyring("", "")
+	assert.Error(t, err)
+
+	_, err = LoadKeyring("", "invalid-key")
+	assert.Error(t, err)
+
+	keyring, err := LoadKeyring("", string(oldKey))
+	assert.NoError(t, err)
+	encrypted, err := keyring.Encrypt("test-token")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "default."))
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProviderConfig is one entry of the identity providers file
+type OIDCProviderConfig struct {
+	// Name is used in the sign-in routes, e.g. "keycloak" for /auth/signin/keycloak
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	RedirectURL  string `json:"redirectUrl"`
+	// Defaults to email and profile. Most providers only grant a refresh token with offline_access.
+	Scopes []string `json:"scopes"`
+}
+
+// OIDCProvider signs users in with any OpenID Connect provider, found through its discovery document
+type OIDCProvider struct {
+	name     string
+	provider *oidc.Provider
+	config   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	states   StateStore
+}
+
+// Checking if OIDCProvider implements IdentityProvider during compile time
+var _ IdentityProvider = (*OIDCProvider)(nil)
+
+// NewOIDCProvider fetches the issuer's discovery document, so the issuer must be reachable
+func NewOIDCProvider(ctx context.Context, config OIDCProviderConfig, states StateStore) (*OIDCProvider, error) {
+	if config.Name == "" || config.Issuer == "" || config.ClientID == "" {
+		return nil, errors.New("identity providers need a name, issuer and client ID")
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider %s: %w", config.Name, err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+
+	return &OIDCProvider{
+		name:     config.Name,
+		provider: provider,
+		config: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+			Endpoint:     provider.Endpoint(),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		states:   states,
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, linkUserID string) (string, error) {
+	return startSignIn(ctx, p.states, p.name, p.config, true, linkUserID)
+}
+
+// Callback verifies the ID token's signature, issuer, audience, expiry and nonce before trusting its claims
+func (p *OIDCProvider) Callback(ctx context.Context, code, state string) (*Identity, *SignInState, error) {
+	token, signIn, err := finishSignIn(ctx, p.states, p.name, p.config, code, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errors.New("no id_token in token response")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+	if idToken.Nonce != signIn.Nonce {
+		return nil, nil, errors.New("ID token nonce does not match")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("failed to read ID token claims: %w", err)
+	}
+
+	// Some providers leave the profile out of the ID token and only serve it from the userinfo endpoint
+	if claims.Email == "" {
+		userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		// The spec requires checking this, otherwise a token for someone else could lend us their profile
+		if userInfo.Subject != idToken.Subject {
+			return nil, nil, errors.New("user info subject does not match the ID token")
+		}
+		if err := userInfo.Claims(&claims); err != nil {
+			return nil, nil, fmt.Errorf("failed to read user info claims: %w", err)
+		}
+	}
+
+	return &Identity{
+		Provider: p.name,
+		Subject:  idToken.Subject,
+		Email:    claims.Email,
+		// An address is only verified if the provider says so, verified addresses are linked to existing accounts
+		EmailVerified: claims.EmailVerified != nil && *claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		RefreshToken:  token.RefreshToken,
+	}, signIn, nil
+}
+
+func (p *OIDCProvider) TokenSource(ctx context.Context, refreshToken string) oauth2.TokenSource {
+	return refreshTokenSource(ctx, p.config, refreshToken)
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOIDCServer is a minimal OpenID Connect provider with discovery, JWKS and a PKCE checking token endpoint
+type stubOIDCServer struct {
+	*httptest.Server
+	key           *rsa.PrivateKey
+	codeChallenge string
+	// Claims of the next ID token, iss, aud, exp and iat are filled in unless set
+	claims map[string]any
+	// Claims served by the userinfo endpoint
+	userInfo map[string]any
+}
+
+func newStubOIDCServer(t *testing.T) *stubOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	stub := &stubOIDCServer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                stub.URL,
+			"authorization_endpoint":                stub.URL + "/authorize",
+			"token_endpoint":                        stub.URL + "/token",
+			"jwks_uri":                              stub.URL + "/jwks",
+			"userinfo_endpoint":                     stub.URL + "/userinfo",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") == "refresh_token" {
+			if r.FormValue("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "refreshed", "token_type": "Bearer", "expires_in": 3600})
+			return
+		}
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "good-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != stub.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh",
+			"id_token":      stub.signIDToken(t),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(stub.userInfo)
+	})
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *stubOIDCServer) signIDToken(t *testing.T) string {
+	claims := map[string]any{
+		"iss": s.URL,
+		"aud": "next-watch",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for name, value := range s.claims {
+		claims[name] = value
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// startSignIn begins a sign-in and remembers what the stub needs from the authorization URL
+func (s *stubOIDCServer) startSignIn(t *testing.T, provider *OIDCProvider, linkUserID string) (state, nonce string) {
+	authorizationURL, err := provider.AuthorizationURL(context.Background(), linkUserID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(authorizationURL, s.URL+"/authorize"))
+
+	parsed, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Contains(t, query.Get("scope"), "openid")
+	s.codeChallenge = query.Get("code_challenge")
+	return query.Get("state"), query.Get("nonce")
+}
+
+func TestOIDCProvider_Callback(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	ctx := context.Background()
+
+	provider, err := NewOIDCProvider(ctx, OIDCProviderConfig{
+		Name:        "keycloak",
+		Issuer:      stub.URL,
+		ClientID:    "next-watch",
+		RedirectURL: "http://localhost/auth/callback/keycloak",
+	}, NewMemoryStateStore())
+	require.NoError(t, err)
+	assert.Equal(t, "keycloak", provider.Name())
+
+	tests := []struct {
+		name     string
+		claims   func(nonce string) map[string]any
+		userInfo map[string]any
+		code     string
+		want     *Identity
+		wantErr  bool
+	}{
+		{
+			name: "Success",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce, "email": "ada@example.com", "email_verified": true, "name": "Ada"}
+			},
+			code: "good-code",
+			want: &Identity{Provider: "keycloak", Subject: "user-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada", RefreshToken: "refresh"},
+		},
+		{
+			name: "Success - Email From Userinfo",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce}
+			},
+			userInfo: map[string]any{"sub": "user-1", "email": "from-userinfo@example.com", "email_verified": true},
+			code:     "good-code",
+			want:     &Identity{Provider: "keycloak", Subject: "user-1", Email: "from-userinfo@example.com", EmailVerified: true, RefreshToken: "refresh"},
+		},
+		{
+			name: "Missing email_verified Is Unverified",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce, "email": "ada@example.com"}
+			},
+			code: "good-code",
+			want: &Identity{Provider: "keycloak", Subject: "user-1", Email: "ada@example.com", EmailVerified: false, RefreshToken: "refresh"},
+		},
+		{
+			name: "Error - Userinfo Of Another Subject",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce}
+			},
+			userInfo: map[string]any{"sub": "user-2", "email": "victim@example.com", "email_verified": true},
+			code:     "good-code",
+			wantErr:  true,
+		},
+		{
+			name: "Unverified Email",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce, "email": "ada@example.com", "email_verified": false}
+			},
+			code: "good-code",
+			want: &Identity{Provider: "keycloak", Subject: "user-1", Email: "ada@example.com", EmailVerified: false, RefreshToken: "refresh"},
+		},
+		{
+			name: "Error - Wrong Nonce",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": "replayed", "email": "ada@example.com"}
+			},
+			code:    "good-code",
+			wantErr: true,
+		},
+		{
+			name: "Error - Wrong Audience",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce, "aud": "another-app"}
+			},
+			code:    "good-code",
+			wantErr: true,
+		},
+		{
+			name: "Error - Expired ID Token",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce, "exp": time.Now().Add(-time.Hour).Unix()}
+			},
+			code:    "good-code",
+			wantErr: true,
+		},
+		{
+			name: "Error - Code Rejected",
+			claims: func(nonce string) map[string]any {
+				return map[string]any{"sub": "user-1", "nonce": nonce}
+			},
+			code:    "bad-code",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, nonce := stub.startSignIn(t, provider, "")
+			stub.claims = tt.claims(nonce)
+			stub.userInfo = tt.userInfo
+
+			got, _, err := provider.Callback(ctx, tt.code, state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OIDCProvider.Callback() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Success - Remembers Account To Link", func(t *testing.T) {
+		state, nonce := stub.startSignIn(t, provider, "user-id")
+		stub.claims = map[string]any{"sub": "user-1", "nonce": nonce, "email": "ada@example.com"}
+
+		_, signIn, err := provider.Callback(ctx, "good-code", state)
+		require.NoError(t, err)
+		assert.Equal(t, "user-id", signIn.LinkUserID)
+	})
+
+	t.Run("Success - Refreshes Access Token", func(t *testing.T) {
+		token, err := provider.TokenSource(ctx, "refresh").Token()
+		require.NoError(t, err)
+		assert.Equal(t, "refreshed", token.AccessToken)
+		// Providers that don't rotate leave the refresh token out of the response
+		assert.Equal(t, "refresh", token.RefreshToken)
+
+		_, err = provider.TokenSource(ctx, "revoked").Token()
+		assert.Error(t, err)
+	})
+
+	t.Run("Error - Unknown State", func(t *testing.T) {
+		_, _, err := provider.Callback(ctx, "good-code", "unknown")
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("Error - State Of Another Provider", func(t *testing.T) {
+		states := NewMemoryStateStore()
+		other, err := NewOIDCProvider(ctx, OIDCProviderConfig{Name: "other", Issuer: stub.URL, ClientID: "next-watch"}, states)
+		require.NoError(t, err)
+		keycloak, err := NewOIDCProvider(ctx, OIDCProviderConfig{Name: "keycloak", Issuer: stub.URL, ClientID: "next-watch"}, states)
+		require.NoError(t, err)
+
+		state, _ := stub.startSignIn(t, other, "")
+		_, _, err = keycloak.Callback(ctx, "good-code", state)
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+}
+
+func TestNewProviderRegistry(t *testing.T) {
+	states := NewMemoryStateStore()
+
+	registry, err := NewProviderRegistry(NewGoogleAuthClient(GoogleConfig{}, states))
+	assert.NoError(t, err)
+	assert.Contains(t, registry, "google")
+
+	_, err = NewProviderRegistry(NewGoogleAuthClient(GoogleConfig{}, states), NewGoogleAuthClient(GoogleConfig{}, states))
+	assert.Error(t, err)
+
+	// Local accounts work without any provider
+	registry, err = NewProviderRegistry()
+	assert.NoError(t, err)
+	assert.Empty(t, registry)
+}
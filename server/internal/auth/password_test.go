@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+	other, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salts must differ")
+
+	ok, err := VerifyPassword(hash, "correct horse battery staple")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyPassword(hash, "Correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyPassword_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "Empty", hash: ""},
+		{name: "Other Algorithm", hash: "$2a$10$abcdefghijklmnopqrstuv"},
+		{name: "Other Version", hash: "$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5"},
+		{name: "Bad Parameters", hash: "$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5"},
+		{name: "Bad Salt", hash: "$argon2id$v=19$m=19456,t=2,p=1$!!$a2V5"},
+		{name: "Missing Key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword(tt.hash, "password")
+			assert.Error(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
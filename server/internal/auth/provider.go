@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// How long a user has to finish signing in with a provider
+const StateTTL = 10 * time.Minute
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// Identity is who a provider says signed in
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	// Only set when the provider granted offline access, lets the app call the provider later on the user's behalf
+	RefreshToken string
+}
+
+// IdentityProvider is somewhere users can sign in, e.g. Google or a corporate Keycloak.
+// Its name is used in the /auth/signin/{provider} and /auth/callback/{provider} routes.
+type IdentityProvider interface {
+	Name() string
+	// AuthorizationURL starts a sign-in and returns the provider page to send the user to.
+	// A non-empty linkUserID links the provider to that user's account instead.
+	AuthorizationURL(ctx context.Context, linkUserID string) (string, error)
+	// Callback finishes a sign-in, failing with ErrInvalidState if the state is unknown or expired.
+	// It also returns what was remembered when the sign-in started.
+	Callback(ctx context.Context, code, state string) (*Identity, *SignInState, error)
+	// TokenSource gets fresh access tokens with a refresh token from an earlier Callback
+	TokenSource(ctx context.Context, refreshToken string) oauth2.TokenSource
+}
+
+// startSignIn saves a new PKCE verifier (and nonce, for OIDC) and returns the authorization URL
+func startSignIn(ctx context.Context, states StateStore, provider string, config *oauth2.Config, withNonce bool, linkUserID string, extra ...oauth2.AuthCodeOption) (string, error) {
+	codeVerifier, verifierErr := randomBytesInHex(32)
+	if verifierErr != nil {
+		return "", fmt.Errorf("could not create a code verifier: %v", verifierErr)
+	}
+
+	sha2 := sha256.New()
+	io.WriteString(sha2, codeVerifier)
+	codeChallenge := base64.RawURLEncoding.EncodeToString(sha2.Sum(nil))
+
+	state, err := randomBytesInHex(24)
+	if err != nil {
+		return "", fmt.Errorf("could not generate random state: %v", err)
+	}
+
+	signIn := SignInState{Provider: provider, CodeVerifier: codeVerifier, LinkUserID: linkUserID}
+	options := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+	}
+	options = append(options, extra...)
+	if withNonce {
+		if signIn.Nonce, err = randomBytesInHex(16); err != nil {
+			return "", fmt.Errorf("could not generate nonce: %v", err)
+		}
+		options = append(options, oauth2.SetAuthURLParam("nonce", signIn.Nonce))
+	}
+
+	if err := states.Save(ctx, state, signIn, StateTTL); err != nil {
+		return "", fmt.Errorf("could not save sign-in state: %w", err)
+	}
+	return config.AuthCodeURL(state, options...), nil
+}
+
+// finishSignIn takes the sign-in of state and exchanges the authorization code with its verifier
+func finishSignIn(ctx context.Context, states StateStore, provider string, config *oauth2.Config, code, state string) (*oauth2.Token, *SignInState, error) {
+	signIn, err := states.Take(ctx, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A state started for another provider must not be accepted here
+	if signIn.Provider != provider {
+		return nil, nil, ErrInvalidState
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", signIn.CodeVerifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while exchanging token: %v", err)
+	}
+	return token, signIn, nil
+}
+
+// refreshTokenSource refreshes when the access token expired, starting from only a refresh token
+func refreshTokenSource(ctx context.Context, config *oauth2.Config, refreshToken string) oauth2.TokenSource {
+	return config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken})
+}
+
+// LoadOIDCProviders reads a JSON list of OIDCProviderConfig from path and discovers each provider.
+// Environment variables in the file, e.g. "${KEYCLOAK_CLIENT_SECRET}", are expanded.
+// An empty path returns no providers.
+func LoadOIDCProviders(ctx context.Context, path string, states StateStore) ([]IdentityProvider, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity providers file: %w", err)
+	}
+	var configs []OIDCProviderConfig
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse identity providers file: %w", err)
+	}
+
+	providers := make([]IdentityProvider, 0, len(configs))
+	for _, config := range configs {
+		provider, err := NewOIDCProvider(ctx, config, states)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// ProviderRegistry looks identity providers up by name. It can be empty when everyone signs in with a password.
+type ProviderRegistry map[string]IdentityProvider
+
+func NewProviderRegistry(providers ...IdentityProvider) (ProviderRegistry, error) {
+	registry := make(ProviderRegistry, len(providers))
+	for _, provider := range providers {
+		name := provider.Name()
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid identity provider name %q, use lowercase letters, digits and dashes", name)
+		}
+		if _, exists := registry[name]; exists {
+			return nil, fmt.Errorf("identity provider %q is configured twice", name)
+		}
+		registry[name] = provider
+	}
+	return registry, nil
+}
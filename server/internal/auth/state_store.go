@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrInvalidState is returned for an OAuth state that is unknown, already used or expired
+var ErrInvalidState = errors.New("unknown or expired sign-in state")
+
+// SignInState is what a sign-in needs to remember between the redirect to the provider and its callback
+type SignInState struct {
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	// Set when a signed in user started this to link the provider to their account instead of signing in
+	LinkUserID string
+}
+
+// StateStore keeps the SignInState of each sign-in, keyed by the OAuth state parameter.
+// Every state can be taken once.
+type StateStore interface {
+	Save(ctx context.Context, state string, signIn SignInState, ttl time.Duration) error
+	// Take returns the sign-in of state and removes it, or ErrInvalidState
+	Take(ctx context.Context, state string) (*SignInState, error)
+	// Sweep removes states that expired before now
+	Sweep(ctx context.Context, now time.Time) error
+}
+
+// RunStateSweeper sweeps store every interval until ctx is done
+func RunStateSweeper(ctx context.Context, store StateStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := store.Sweep(ctx, now); err != nil {
+				log.Printf("Failed to sweep expired sign-in states: %v", err)
+			}
+		}
+	}
+}
+
+type memoryState struct {
+	signIn    SignInState
+	expiresAt time.Time
+}
+
+// MemoryStateStore keeps states in process, so it only works with a single replica
+type MemoryStateStore struct {
+	states map[string]memoryState
+	mu     sync.Mutex
+}
+
+// Checking if MemoryStateStore implements StateStore during compile time
+var _ StateStore = (*MemoryStateStore)(nil)
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string]memoryState)}
+}
+
+func (s *MemoryStateStore) Save(ctx context.Context, state string, signIn SignInState, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state] = memoryState{signIn: signIn, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStateStore) Take(ctx context.Context, state string) (*SignInState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.states[state]
+	if !exists {
+		return nil, ErrInvalidState
+	}
+	delete(s.states, state)
+	if !time.Now().Before(entry.expiresAt) {
+		return nil, ErrInvalidState
+	}
+	return &entry.signIn, nil
+}
+
+func (s *MemoryStateStore) Sweep(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for state, entry := range s.states {
+		if !now.Before(entry.expiresAt) {
+			delete(s.states, state)
+		}
+	}
+	return nil
+}
+
+// PostgresStateStore keeps states in the oauth_states table so any replica can finish a sign-in. The PKCE
+// verifier and nonce are sealed with the keyring, so reading the table isn't enough to finish someone's sign-in.
+type PostgresStateStore struct {
+	db      *sql.DB
+	keyring *Keyring
+}
+
+// Checking if PostgresStateStore implements StateStore during compile time
+var _ StateStore = (*PostgresStateStore)(nil)
+
+func NewPostgresStateStore(db *sql.DB, keyring *Keyring) *PostgresStateStore {
+	return &PostgresStateStore{db: db, keyring: keyring}
+}
+
+func (s *PostgresStateStore) Save(ctx context.Context, state string, signIn SignInState, ttl time.Duration) error {
+	codeVerifier, err := s.keyring.Encrypt(signIn.CodeVerifier)
+	if err != nil {
+		return err
+	}
+	nonce, err := s.keyring.Encrypt(signIn.Nonce)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO oauth_states (state, provider, code_verifier, nonce, link_user_id, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err = s.db.ExecContext(ctx, query, state, signIn.Provider, codeVerifier, nonce, signIn.LinkUserID, time.Now().Add(ttl))
+	return err
+}
+
+func (s *PostgresStateStore) Take(ctx context.Context, state string) (*SignInState, error) {
+	// Deleting and returning in one statement makes sure two callbacks can't both use the state
+	query := `DELETE FROM oauth_states
+              WHERE state = $1
+              RETURNING provider, code_verifier, nonce, link_user_id, expires_at`
+
+	var signIn SignInState
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, query, state).Scan(&signIn.Provider, &signIn.CodeVerifier, &signIn.Nonce, &signIn.LinkUserID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(expiresAt) {
+		return nil, ErrInvalidState
+	}
+
+	// States only live for minutes, so one sealed with a key that has since been rotated out, or saved in the
+	// clear before states were sealed, fails like an expired one and the user simply signs in again
+	if signIn.CodeVerifier, err = s.keyring.Decrypt(signIn.CodeVerifier); err != nil {
+		return nil, ErrInvalidState
+	}
+	if signIn.Nonce, err = s.keyring.Decrypt(signIn.Nonce); err != nil {
+		return nil, ErrInvalidState
+	}
+	return &signIn, nil
+}
+
+func (s *PostgresStateStore) Sweep(ctx context.Context, now time.Time) error {
+	query := `DELETE FROM oauth_states
+              WHERE expires_at <= $1`
+
+	_, err := s.db.ExecContext(ctx, query, now)
+	return err
+}
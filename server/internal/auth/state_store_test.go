@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sealedAs matches an argument that the keyring decrypts to plaintext
+type sealedAs struct {
+	keyring   *Keyring
+	plaintext string
+}
+
+func (s sealedAs) Match(v driver.Value) bool {
+	ciphertext, ok := v.(string)
+	if !ok {
+		return false
+	}
+	plaintext, err := s.keyring.Decrypt(ciphertext)
+	return err == nil && plaintext == s.plaintext
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("State can be taken once", func(t *testing.T) {
+		store := NewMemoryStateStore()
+		signIn := SignInState{Provider: "google", CodeVerifier: "verifier", Nonce: "nonce"}
+		assert.NoError(t, store.Save(ctx, "state", signIn, time.Minute))
+
+		got, err := store.Take(ctx, "state")
+		assert.NoError(t, err)
+		assert.Equal(t, &signIn, got)
+
+		_, err = store.Take(ctx, "state")
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("Expired state is rejected", func(t *testing.T) {
+		store := NewMemoryStateStore()
+		assert.NoError(t, store.Save(ctx, "state", SignInState{CodeVerifier: "verifier"}, -time.Second))
+
+		_, err := store.Take(ctx, "state")
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("Sweep removes only expired states", func(t *testing.T) {
+		store := NewMemoryStateStore()
+		assert.NoError(t, store.Save(ctx, "abandoned", SignInState{CodeVerifier: "verifier"}, time.Minute))
+		assert.NoError(t, store.Save(ctx, "pending", SignInState{CodeVerifier: "verifier"}, time.Hour))
+
+		assert.NoError(t, store.Sweep(ctx, time.Now().Add(30*time.Minute)))
+		assert.Len(t, store.states, 1)
+		assert.Contains(t, store.states, "pending")
+	})
+}
+
+var stateRowColumns = []string{"provider", "code_verifier", "nonce", "link_user_id", "expires_at"}
+
+func TestPostgresStateStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	store := NewPostgresStateStore(db, keyring)
+	ctx := context.Background()
+
+	seal := func(plaintext string) string {
+		ciphertext, err := keyring.Encrypt(plaintext)
+		require.NoError(t, err)
+		return ciphertext
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *SignInState
+		wantErr   error
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(stateRowColumns).AddRow("keycloak", seal("verifier"), seal("nonce"), "user-id", time.Now().Add(time.Minute))
+				mock.ExpectQuery("^DELETE FROM oauth_states WHERE state = \\$1 RETURNING").WithArgs("state").WillReturnRows(rows)
+			},
+			want:    &SignInState{Provider: "keycloak", CodeVerifier: "verifier", Nonce: "nonce", LinkUserID: "user-id"},
+			wantErr: nil,
+		},
+		{
+			name: "Saved In The Clear",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(stateRowColumns).AddRow("keycloak", "verifier", "nonce", "", time.Now().Add(time.Minute))
+				mock.ExpectQuery("^DELETE FROM oauth_states WHERE state = \\$1 RETURNING").WithArgs("state").WillReturnRows(rows)
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "Expired",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(stateRowColumns).AddRow("keycloak", "verifier", "nonce", "", time.Now().Add(-time.Minute))
+				mock.ExpectQuery("^DELETE FROM oauth_states WHERE state = \\$1 RETURNING").WithArgs("state").WillReturnRows(rows)
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "Unknown",
+			mockSetup: func() {
+				mock.ExpectQuery("^DELETE FROM oauth_states WHERE state = \\$1 RETURNING").WithArgs("state").WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^DELETE FROM oauth_states WHERE state = \\$1 RETURNING").WithArgs("state").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := store.Take(ctx, "state")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	mock.ExpectExec("^INSERT INTO oauth_states").WithArgs("state", "keycloak", sealedAs{keyring, "verifier"}, sealedAs{keyring, "nonce"}, "user-id", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.Save(ctx, "state", SignInState{Provider: "keycloak", CodeVerifier: "verifier", Nonce: "nonce", LinkUserID: "user-id"}, time.Minute))
+
+	now := time.Now()
+	mock.ExpectExec("^DELETE FROM oauth_states WHERE expires_at <= \\$1").WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 3))
+	assert.NoError(t, store.Sweep(ctx, now))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,453 @@
+// Package config loads the server's configuration once at startup. Every setting has a name like DATABASE_URL
+// and is read, in increasing precedence, from its default, a JSON config file, the environment variable of
+// that name and the flag -database-url. Secrets can instead be read from a file named by e.g. DATABASE_URL_FILE.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/querylimit"
+	"github.com/Azanul/Next-Watch/internal/services"
+)
+
+// Config is everything the server is configured with, validated by Load
+type Config struct {
+	Port string
+	// Links in verification and password reset emails point here
+	PublicURL string
+	// Origins of the frontends allowed to call the API from the browser
+	CORSOrigins []string
+	// "-" logs to stderr
+	LogFile string
+
+	DatabaseURL string
+	// Built from ENCRYPTION_KEYS, or the single raw ENCRYPTION_KEY of deployments that predate rotation.
+	// Only set when keys are configured, which OAUTH_STATE_STORE=postgres requires. Without it provider refresh tokens aren't kept.
+	Keyring *auth.Keyring
+
+	TasteWeighting    services.TasteWeighting
+	RolesFile         string
+	AuthProvidersFile string
+	// "postgres" lets any replica handle the OAuth callback, "memory" is for single instance setups
+	OAuthStateStore string
+	// Google sign-in is enabled when Google.ClientID is set
+	Google auth.GoogleConfig
+
+	Session          SessionConfig
+	Mail             MailConfig
+	PersistedQueries PersistedQueriesConfig
+	QueryLimits      QueryLimitsConfig
+}
+
+type SessionConfig struct {
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+	// Cookies are only sent over HTTPS, on unless PUBLIC_URL is plain http
+	SecureCookies bool
+}
+
+// MailConfig picks how emails are sent: "log" prints them, "file" writes them to Dir and "smtp" sends them through SMTPHost
+type MailConfig struct {
+	Mailer       string
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// PersistedQueriesConfig picks where automatic persisted queries are kept: "memory" in process, "postgres" also
+// serves the queries registered with cmd/register-queries from the database. Only accepts just the registered queries.
+type PersistedQueriesConfig struct {
+	Store string
+	Only  bool
+}
+
+type QueryLimitsConfig struct {
+	MaxDepth int
+	MaxCost  int
+}
+
+type setting struct {
+	name     string
+	usage    string
+	fallback string
+	// Can be read from the file named by name + "_FILE" instead
+	secret bool
+}
+
+var settings = []setting{
+	{name: "PORT", usage: "port to listen on", fallback: "8080"},
+	{name: "PUBLIC_URL", usage: "URL the app is reached at, defaults to http://localhost:PORT"},
+	{name: "CORS_ORIGINS", usage: "comma separated origins allowed to call the API from the browser", fallback: "http://localhost:64139"},
+	{name: "LOG_FILE", usage: `file to append logs to, "-" for stderr`, fallback: "app.log"},
+	{name: "DATABASE_URL", usage: "Postgres connection string", secret: true},
+	{name: "ENCRYPTION_KEYS", usage: "comma separated id:base64 keys sealing sign-in states and provider refresh tokens, the first one encrypts", secret: true},
+	{name: "ENCRYPTION_KEY", usage: "single raw key, for deployments that predate ENCRYPTION_KEYS", secret: true},
+	{name: "TASTE_WEIGHTING", usage: "legacy or normalized", fallback: "legacy"},
+	{name: "ROLES_FILE", usage: "JSON file overriding the permissions of roles"},
+	{name: "AUTH_PROVIDERS_FILE", usage: "JSON file listing OpenID Connect providers"},
+	{name: "OAUTH_STATE_STORE", usage: "postgres or memory", fallback: "postgres"},
+	{name: "GOOGLE_CLIENT_ID", usage: "enables Google sign-in"},
+	{name: "GOOGLE_CLIENT_SECRET", usage: "secret of the Google OAuth client", secret: true},
+	{name: "GOOGLE_REDIRECT_URL", usage: "callback URL registered with Google"},
+	{name: "SESSION_IDLE_TIMEOUT", usage: "how long an unused session stays signed in", fallback: services.DefaultSessionIdleTimeout.String()},
+	{name: "SESSION_ABSOLUTE_TIMEOUT", usage: "how long any session stays signed in", fallback: services.DefaultSessionAbsoluteTimeout.String()},
+	{name: "SECURE_COOKIES", usage: "only send cookies over HTTPS, defaults to whether PUBLIC_URL is https"},
+	{name: "MAILER", usage: "log, file or smtp", fallback: "log"},
+	{name: "MAIL_FROM", usage: "sender of emails", fallback: "Next Watch <noreply@localhost>"},
+	{name: "MAIL_DIR", usage: "directory the file mailer writes to", fallback: "mail"},
+	{name: "SMTP_HOST", usage: "SMTP server, required by the smtp mailer"},
+	{name: "SMTP_PORT", usage: "SMTP port", fallback: "587"},
+	{name: "SMTP_USERNAME", usage: "SMTP user"},
+	{name: "SMTP_PASSWORD", usage: "SMTP password", secret: true},
+	{name: "PERSISTED_QUERY_STORE", usage: "memory or postgres", fallback: "memory"},
+	{name: "PERSISTED_QUERIES_ONLY", usage: "only accept registered queries, needs PERSISTED_QUERY_STORE=postgres", fallback: "false"},
+	{name: "MAX_QUERY_DEPTH", usage: "deepest GraphQL operation accepted", fallback: strconv.Itoa(querylimit.DefaultMaxDepth)},
+	{name: "MAX_QUERY_COST", usage: "costliest GraphQL operation accepted", fallback: strconv.Itoa(querylimit.DefaultMaxCost)},
+}
+
+// Load reads the configuration from args, the config file named by -config or CONFIG_FILE, and getenv,
+// then validates all of it. The error lists every invalid setting.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	values, err := loadValues(args, getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{values: values}
+	cfg := &Config{
+		Port:              p.port("PORT"),
+		CORSOrigins:       p.origins("CORS_ORIGINS"),
+		LogFile:           p.string("LOG_FILE"),
+		DatabaseURL:       p.required("DATABASE_URL", p.secret("DATABASE_URL")),
+		RolesFile:         p.file("ROLES_FILE"),
+		AuthProvidersFile: p.file("AUTH_PROVIDERS_FILE"),
+		OAuthStateStore:   p.oneOf("OAUTH_STATE_STORE", "postgres", "memory"),
+		Google: auth.GoogleConfig{
+			ClientID:     p.string("GOOGLE_CLIENT_ID"),
+			ClientSecret: p.secret("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  p.url("GOOGLE_REDIRECT_URL"),
+		},
+		Session: SessionConfig{
+			IdleTimeout:     p.duration("SESSION_IDLE_TIMEOUT"),
+			AbsoluteTimeout: p.duration("SESSION_ABSOLUTE_TIMEOUT"),
+		},
+		Mail: MailConfig{
+			Mailer:       p.oneOf("MAILER", "log", "file", "smtp"),
+			From:         p.address("MAIL_FROM"),
+			Dir:          p.string("MAIL_DIR"),
+			SMTPHost:     p.string("SMTP_HOST"),
+			SMTPPort:     p.port("SMTP_PORT"),
+			SMTPUsername: p.string("SMTP_USERNAME"),
+			SMTPPassword: p.secret("SMTP_PASSWORD"),
+		},
+		PersistedQueries: PersistedQueriesConfig{
+			Store: p.oneOf("PERSISTED_QUERY_STORE", "memory", "postgres"),
+			Only:  p.bool("PERSISTED_QUERIES_ONLY"),
+		},
+		QueryLimits: QueryLimitsConfig{
+			MaxDepth: p.positive("MAX_QUERY_DEPTH"),
+			MaxCost:  p.positive("MAX_QUERY_COST"),
+		},
+	}
+
+	cfg.PublicURL = p.url("PUBLIC_URL")
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = "http://localhost:" + cfg.Port
+	}
+	cfg.Session.SecureCookies = strings.HasPrefix(cfg.PublicURL, "https://")
+	if values["SECURE_COOKIES"] != "" {
+		cfg.Session.SecureCookies = p.bool("SECURE_COOKIES")
+	}
+
+	cfg.TasteWeighting, err = services.ParseTasteWeighting(values["TASTE_WEIGHTING"])
+	if err != nil {
+		p.fail("TASTE_WEIGHTING", "%v", err)
+	}
+
+	// The keyring seals the sign-in states kept in Postgres, states kept in memory never leave the process
+	keys, legacyKey := p.secret("ENCRYPTION_KEYS"), p.secret("ENCRYPTION_KEY")
+	if keys == "" && legacyKey == "" {
+		if cfg.OAuthStateStore == "postgres" {
+			p.fail("ENCRYPTION_KEYS", "is required when OAUTH_STATE_STORE is postgres")
+		}
+	} else if cfg.Keyring, err = auth.LoadKeyring(keys, legacyKey); err != nil {
+		p.fail("ENCRYPTION_KEYS", "%v", err)
+	}
+
+	if cfg.Google.ClientID != "" {
+		if cfg.Google.ClientSecret == "" {
+			p.fail("GOOGLE_CLIENT_SECRET", "is required when GOOGLE_CLIENT_ID is set")
+		}
+		if cfg.Google.RedirectURL == "" {
+			p.fail("GOOGLE_REDIRECT_URL", "is required when GOOGLE_CLIENT_ID is set")
+		}
+	}
+	if cfg.Session.IdleTimeout > cfg.Session.AbsoluteTimeout {
+		p.fail("SESSION_IDLE_TIMEOUT", "cannot exceed SESSION_ABSOLUTE_TIMEOUT")
+	}
+	if cfg.Mail.Mailer == "smtp" && cfg.Mail.SMTPHost == "" {
+		p.fail("SMTP_HOST", "is required when MAILER is smtp")
+	}
+	if cfg.PersistedQueries.Only && cfg.PersistedQueries.Store != "postgres" {
+		p.fail("PERSISTED_QUERIES_ONLY", "needs PERSISTED_QUERY_STORE=postgres, registered queries are kept in the database")
+	}
+
+	if len(p.errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(p.errs...))
+	}
+	return cfg, nil
+}
+
+// LoadDatabaseURL reads just DATABASE_URL like Load does, for tools that only need the database
+func LoadDatabaseURL(getenv func(string) string) (string, error) {
+	values, err := loadValues(nil, getenv)
+	if err != nil {
+		return "", err
+	}
+	p := &parser{values: values}
+	databaseURL := p.required("DATABASE_URL", p.secret("DATABASE_URL"))
+	if len(p.errs) > 0 {
+		return "", errors.Join(p.errs...)
+	}
+	return databaseURL, nil
+}
+
+// flagName turns a setting name like DATABASE_URL into its flag, database-url
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// loadValues merges the raw values of all settings, later sources overriding earlier ones
+func loadValues(args []string, getenv func(string) string) (map[string]string, error) {
+	values := make(map[string]string)
+	known := make(map[string]bool)
+	for _, s := range settings {
+		values[s.name] = s.fallback
+		known[s.name] = true
+		if s.secret {
+			known[s.name+"_FILE"] = true
+		}
+	}
+
+	flags := flag.NewFlagSet("next-watch", flag.ContinueOnError)
+	configFile := flags.String("config", "", "JSON file of settings, keyed like the environment variables")
+	byFlag := make(map[string]string, len(known))
+	for _, s := range settings {
+		flags.String(flagName(s.name), "", s.usage)
+		byFlag[flagName(s.name)] = s.name
+		if s.secret {
+			flags.String(flagName(s.name+"_FILE"), "", "file holding "+s.name)
+			byFlag[flagName(s.name+"_FILE")] = s.name + "_FILE"
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		fileValues, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range fileValues {
+			if !known[name] {
+				return nil, fmt.Errorf("config file %s: unknown setting %s", path, name)
+			}
+			values[name] = value
+		}
+	}
+
+	for name := range known {
+		if value := getenv(name); value != "" {
+			values[name] = value
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if name, ok := byFlag[f.Name]; ok {
+			values[name] = f.Value.String()
+		}
+	})
+	return values, nil
+}
+
+// readFile reads a JSON object of settings, whose values may be strings, numbers or booleans
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch value := value.(type) {
+		case string:
+			values[name] = value
+		case float64, bool:
+			values[name] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("config file %s: %s must be a string, number or boolean", path, name)
+		}
+	}
+	return values, nil
+}
+
+// parser converts raw values, collecting an error per invalid setting instead of stopping at the first
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) fail(name, format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) string(name string) string {
+	return p.values[name]
+}
+
+// secret returns the setting, or the contents of the file named by its _FILE setting
+func (p *parser) secret(name string) string {
+	path := p.values[name+"_FILE"]
+	if path == "" {
+		return p.values[name]
+	}
+	if p.values[name] != "" {
+		p.fail(name, "set either %s or %s_FILE, not both", name, name)
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.fail(name+"_FILE", "%v", err)
+		return ""
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		p.fail(name+"_FILE", "%s is empty", path)
+	}
+	return value
+}
+
+// required checks a value was set, a secret file that couldn't be read has already been reported
+func (p *parser) required(name, value string) string {
+	if value == "" && p.values[name+"_FILE"] == "" {
+		p.fail(name, "is required")
+	}
+	return value
+}
+
+func (p *parser) oneOf(name string, options ...string) string {
+	value := p.values[name]
+	for _, option := range options {
+		if value == option {
+			return value
+		}
+	}
+	last := len(options) - 1
+	p.fail(name, "unknown value %q, use %s or %s", value, strings.Join(options[:last], ", "), options[last])
+	return ""
+}
+
+func (p *parser) bool(name string) bool {
+	value, err := strconv.ParseBool(p.values[name])
+	if err != nil {
+		p.fail(name, "%q is not true or false", p.values[name])
+	}
+	return value
+}
+
+func (p *parser) positive(name string) int {
+	value, err := strconv.Atoi(p.values[name])
+	if err != nil || value < 1 {
+		p.fail(name, "%q is not a positive whole number", p.values[name])
+	}
+	return value
+}
+
+func (p *parser) port(name string) string {
+	value := p.values[name]
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		p.fail(name, "%q is not a port number", value)
+	}
+	return value
+}
+
+func (p *parser) duration(name string) time.Duration {
+	value, err := time.ParseDuration(p.values[name])
+	if err != nil || value <= 0 {
+		p.fail(name, "%q is not a positive duration such as 168h", p.values[name])
+	}
+	return value
+}
+
+// url checks an optional absolute http(s) URL
+func (p *parser) url(name string) string {
+	value := p.values[name]
+	if value == "" {
+		return ""
+	}
+	if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.fail(name, "%q is not an http or https URL", value)
+	}
+	return value
+}
+
+// origins checks a comma separated list of origins such as https://example.com
+func (p *parser) origins(name string) []string {
+	var origins []string
+	for _, origin := range strings.Split(p.values[name], ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			p.fail(name, "%q is not an origin such as https://example.com", origin)
+			continue
+		}
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+	return origins
+}
+
+func (p *parser) address(name string) string {
+	value := p.values[name]
+	if _, err := mail.ParseAddress(value); err != nil {
+		p.fail(name, "%q is not an email address", value)
+	}
+	return value
+}
+
+// file checks that an optional setting names a readable file
+func (p *parser) file(name string) string {
+	value := p.values[name]
+	if value == "" {
+		return ""
+	}
+	if _, err := os.Stat(value); err != nil {
+		p.fail(name, "%v", err)
+	}
+	return value
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validKey is a raw 32 byte ENCRYPTION_KEY
+const validKey = "0123456789abcdef0123456789abcdef"
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{"DATABASE_URL": "postgres://localhost/next_watch", "ENCRYPTION_KEY": validKey}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, "http://localhost:8080", cfg.PublicURL)
+	assert.Equal(t, []string{"http://localhost:64139"}, cfg.CORSOrigins)
+	assert.Equal(t, "app.log", cfg.LogFile)
+	assert.NotNil(t, cfg.Keyring)
+	assert.Equal(t, services.LegacyTasteWeighting, cfg.TasteWeighting)
+	assert.Equal(t, "postgres", cfg.OAuthStateStore)
+	assert.Equal(t, services.DefaultSessionIdleTimeout, cfg.Session.IdleTimeout)
+	assert.False(t, cfg.Session.SecureCookies)
+	assert.Equal(t, "log", cfg.Mail.Mailer)
+	assert.Equal(t, "memory", cfg.PersistedQueries.Store)
+	assert.Equal(t, 10, cfg.QueryLimits.MaxDepth)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	configFile := writeFile(t, "config.json", `{"PORT": 9000, "MAILER": "file", "MAIL_DIR": "from-file", "PERSISTED_QUERIES_ONLY": false}`)
+	secretFile := writeFile(t, "database_url", "postgres://db/next_watch\n")
+
+	cfg, err := Load(
+		[]string{"-mail-dir", "from-flag", "-cors-origins", "https://next-watch.example, http://localhost:3000/"},
+		env(map[string]string{
+			"CONFIG_FILE":          configFile,
+			"MAIL_DIR":             "from-env",
+			"DATABASE_URL_FILE":    secretFile,
+			"ENCRYPTION_KEY":       validKey,
+			"SESSION_IDLE_TIMEOUT": "1h",
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, "http://localhost:9000", cfg.PublicURL)
+	assert.Equal(t, "file", cfg.Mail.Mailer)
+	assert.Equal(t, "from-flag", cfg.Mail.Dir)
+	assert.Equal(t, "postgres://db/next_watch", cfg.DatabaseURL)
+	assert.Equal(t, []string{"https://next-watch.example", "http://localhost:3000"}, cfg.CORSOrigins)
+	assert.Equal(t, time.Hour, cfg.Session.IdleTimeout)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	valid := map[string]string{"DATABASE_URL": "postgres://localhost/next_watch", "ENCRYPTION_KEY": validKey}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		wantErr []string
+	}{
+		{
+			name:    "Missing Required",
+			env:     map[string]string{"DATABASE_URL": "", "ENCRYPTION_KEY": ""},
+			wantErr: []string{"DATABASE_URL: is required", "ENCRYPTION_KEYS: is required"},
+		},
+		{
+			name:    "Short Encryption Key In Memory",
+			env:     map[string]string{"ENCRYPTION_KEY": "short", "OAUTH_STATE_STORE": "memory"},
+			wantErr: []string{"ENCRYPTION_KEYS:"},
+		},
+		{
+			name:    "Every Invalid Setting Is Reported",
+			env:     map[string]string{"PORT": "http", "MAILER": "carrier-pigeon", "MAX_QUERY_COST": "0"},
+			wantErr: []string{"PORT:", "MAILER:", "MAX_QUERY_COST:"},
+		},
+		{
+			name:    "Short Encryption Key",
+			env:     map[string]string{"ENCRYPTION_KEY": "short"},
+			wantErr: []string{"ENCRYPTION_KEYS:"},
+		},
+		{
+			name:    "Wildcard Origin",
+			env:     map[string]string{"CORS_ORIGINS": "*"},
+			wantErr: []string{"CORS_ORIGINS:"},
+		},
+		{
+			name:    "Google Without Secret",
+			env:     map[string]string{"GOOGLE_CLIENT_ID": "client"},
+			wantErr: []string{"GOOGLE_CLIENT_SECRET: is required", "GOOGLE_REDIRECT_URL: is required"},
+		},
+		{
+			name:    "Secret Set Twice",
+			env:     map[string]string{"SMTP_PASSWORD": "hunter2", "SMTP_PASSWORD_FILE": "/run/secrets/smtp"},
+			wantErr: []string{"SMTP_PASSWORD: set either"},
+		},
+		{
+			name:    "Missing Secret File",
+			env:     map[string]string{"DATABASE_URL": "", "DATABASE_URL_FILE": "/nonexistent/database_url"},
+			wantErr: []string{"DATABASE_URL_FILE:"},
+		},
+		{
+			name:    "SMTP Without Host",
+			env:     map[string]string{"MAILER": "smtp"},
+			wantErr: []string{"SMTP_HOST: is required"},
+		},
+		{
+			name:    "Allowlist In Memory",
+			env:     map[string]string{"PERSISTED_QUERIES_ONLY": "true"},
+			wantErr: []string{"PERSISTED_QUERIES_ONLY:"},
+		},
+		{
+			name:    "Idle Longer Than Absolute",
+			env:     map[string]string{"SESSION_IDLE_TIMEOUT": "48h", "SESSION_ABSOLUTE_TIMEOUT": "24h"},
+			wantErr: []string{"SESSION_IDLE_TIMEOUT:"},
+		},
+		{
+			name:    "Missing Roles File",
+			env:     map[string]string{"ROLES_FILE": "/nonexistent/roles.json"},
+			wantErr: []string{"ROLES_FILE:"},
+		},
+		{
+			name:    "Invalid Flag",
+			args:    []string{"-port", "0"},
+			wantErr: []string{"PORT:"},
+		},
+		{
+			name:    "Unknown Flag",
+			args:    []string{"-prot", "8081"},
+			wantErr: []string{"-prot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make(map[string]string)
+			for name, value := range valid {
+				values[name] = value
+			}
+			for name, value := range tt.env {
+				values[name] = value
+			}
+
+			cfg, err := Load(tt.args, env(values))
+			require.Error(t, err)
+			assert.Nil(t, cfg)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestLoad_MemoryStateStoreNeedsNoKeys(t *testing.T) {
+	cfg, err := Load([]string{"-oauth-state-store", "memory"}, env(map[string]string{"DATABASE_URL": "postgres://localhost/next_watch"}))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Keyring)
+}
+
+func TestLoad_SecureCookies(t *testing.T) {
+	valid := map[string]string{"DATABASE_URL": "postgres://localhost/next_watch", "ENCRYPTION_KEY": validKey, "PUBLIC_URL": "https://next-watch.example"}
+
+	cfg, err := Load(nil, env(valid))
+	require.NoError(t, err)
+	assert.True(t, cfg.Session.SecureCookies)
+
+	// E.g. behind a proxy that terminates TLS on another host, or for local testing
+	cfg, err = Load([]string{"-secure-cookies", "false"}, env(valid))
+	require.NoError(t, err)
+	assert.False(t, cfg.Session.SecureCookies)
+}
+
+func TestLoad_UnknownFileSetting(t *testing.T) {
+	configFile := writeFile(t, "config.json", `{"DATABSE_URL": "postgres://localhost/next_watch"}`)
+
+	_, err := Load([]string{"-config", configFile}, env(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown setting DATABSE_URL")
+}
+
+func TestLoadDatabaseURL(t *testing.T) {
+	secretFile := writeFile(t, "database_url", "postgres://db/next_watch\r\n")
+
+	databaseURL, err := LoadDatabaseURL(env(map[string]string{"DATABASE_URL_FILE": secretFile}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/next_watch", databaseURL)
+
+	_, err = LoadDatabaseURL(env(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// Package dataloader batches the lookups GraphQL field resolvers make one at a time,
+// so resolving a list doesn't run a query per item
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// How long a loader waits for more keys before fetching
+	DefaultWait = 2 * time.Millisecond
+	// Batches are fetched right away once they are this big
+	DefaultMaxBatch = 100
+)
+
+// FetchFunc loads the values for a batch of keys. Keys it leaves out of the map load as the zero value.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader collects the keys loaded within a short window and fetches them together.
+// Results are cached for the loader's lifetime, so it should live for a single request.
+type Loader[K comparable, V any] struct {
+	fetch    FetchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	results map[K]*result[V]
+	pending *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	results []*result[V]
+}
+
+func New[K comparable, V any](fetch FetchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     DefaultWait,
+		maxBatch: DefaultMaxBatch,
+		results:  make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, fetching it with the other keys loaded around the same time
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res, ok := l.results[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		l.results[key] = res
+
+		if l.pending == nil {
+			l.pending = &batch[K, V]{}
+			pending := l.pending
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx, pending) })
+		}
+		l.pending.keys = append(l.pending.keys, key)
+		l.pending.results = append(l.pending.results, res)
+		if len(l.pending.keys) >= l.maxBatch {
+			full := l.pending
+			l.pending = nil
+			go l.run(ctx, full)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches b unless it was already fetched because it filled up
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	l.run(ctx, b)
+}
+
+func (l *Loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	values, err := l.fetch(ctx, b.keys)
+	for i, key := range b.keys {
+		res := b.results[i]
+		res.value, res.err = values[key], err
+		close(res.done)
+	}
+}
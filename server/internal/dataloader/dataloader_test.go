@@ -0,0 +1,130 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingFetch doubles every key and remembers the batches it was called with
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (f *recordingFetch) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch := append([]int(nil), keys...)
+	sort.Ints(batch)
+	f.batches = append(f.batches, batch)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	values := make(map[int]int, len(keys))
+	for _, key := range keys {
+		if key >= 0 {
+			values[key] = key * 2
+		}
+	}
+	return values, nil
+}
+
+// loadAll loads keys concurrently, like gqlgen resolves the items of a list
+func loadAll(loader *Loader[int, int], keys []int) ([]int, []error) {
+	values := make([]int, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			values[i], errs[i] = loader.Load(context.Background(), key)
+		}(i, key)
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func TestLoader_Load(t *testing.T) {
+	tests := []struct {
+		name        string
+		keys        []int
+		maxBatch    int
+		fetchErr    error
+		wantValues  []int
+		wantBatches [][]int
+		wantErr     bool
+	}{
+		{
+			name:        "Batches And Deduplicates Keys",
+			keys:        []int{1, 2, 3, 2, 1},
+			maxBatch:    DefaultMaxBatch,
+			wantValues:  []int{2, 4, 6, 4, 2},
+			wantBatches: [][]int{{1, 2, 3}},
+		},
+		{
+			name:        "Missing Keys Load As Zero",
+			keys:        []int{1, -1},
+			maxBatch:    DefaultMaxBatch,
+			wantValues:  []int{2, 0},
+			wantBatches: [][]int{{-1, 1}},
+		},
+		{
+			name:        "Splits Full Batches",
+			keys:        []int{1, 2, 3},
+			maxBatch:    2,
+			wantValues:  []int{2, 4, 6},
+			wantBatches: nil, // Which keys end up together depends on scheduling
+		},
+		{
+			name:        "Error",
+			keys:        []int{1, 2},
+			maxBatch:    DefaultMaxBatch,
+			fetchErr:    errors.New("connection refused"),
+			wantValues:  []int{0, 0},
+			wantBatches: [][]int{{1, 2}},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch := &recordingFetch{err: tt.fetchErr}
+			loader := New(fetch.fetch)
+			loader.maxBatch = tt.maxBatch
+			// Leaves the goroutines plenty of time to join the batch on a busy machine
+			loader.wait = 50 * time.Millisecond
+
+			values, errs := loadAll(loader, tt.keys)
+			assert.Equal(t, tt.wantValues, values)
+			for _, err := range errs {
+				assert.Equal(t, tt.wantErr, err != nil)
+			}
+			if tt.wantBatches != nil {
+				assert.Equal(t, tt.wantBatches, fetch.batches)
+			} else {
+				assert.Len(t, fetch.batches, 2)
+			}
+		})
+	}
+}
+
+func TestLoader_CachesResults(t *testing.T) {
+	fetch := &recordingFetch{}
+	loader := New(fetch.fetch)
+
+	for i := 0; i < 3; i++ {
+		value, err := loader.Load(context.Background(), 4)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, value)
+	}
+	assert.Len(t, fetch.batches, 1)
+}
@@ -0,0 +1,55 @@
+// Package globalid turns database IDs into the IDs the GraphQL API hands out. A global ID names the type of
+// the object, so the ID of a rating can't be passed where a movie is expected, and is opaque so clients
+// don't build or pick apart IDs themselves.
+package globalid
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/google/uuid"
+)
+
+// The GraphQL types that have global IDs
+const (
+	Movie               = "Movie"
+	User                = "User"
+	Rating              = "Rating"
+	Session             = "Session"
+	PersonalAccessToken = "PersonalAccessToken"
+	LinkedIdentity      = "LinkedIdentity"
+)
+
+// Encode returns the global ID of the object of typeName with the given database ID,
+// the URL safe base64 of "Movie:<uuid>" for a movie
+func Encode(typeName string, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(typeName + ":" + id.String()))
+}
+
+// Parse returns the type and database ID of a global ID
+func Parse(globalID string) (string, uuid.UUID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", uuid.Nil, apperr.Validation("invalid ID")
+	}
+	typeName, rawID, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", uuid.Nil, apperr.Validation("invalid ID")
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", uuid.Nil, apperr.Validation("invalid ID")
+	}
+	return typeName, id, nil
+}
+
+// Decode returns the database ID of a global ID, which must be of typeName
+func Decode(typeName, globalID string) (uuid.UUID, error) {
+	gotType, id, err := Parse(globalID)
+	if err != nil || gotType != typeName {
+		return uuid.Nil, apperr.Validation(fmt.Sprintf("invalid %s ID", typeName))
+	}
+	return id, nil
+}
@@ -0,0 +1,79 @@
+package globalid
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	movieID := uuid.New()
+
+	tests := []struct {
+		name     string
+		typeName string
+		globalID string
+		want     uuid.UUID
+		wantErr  bool
+	}{
+		{
+			name:     "Success",
+			typeName: Movie,
+			globalID: Encode(Movie, movieID),
+			want:     movieID,
+		},
+		{
+			name:     "Other Type",
+			typeName: Movie,
+			globalID: Encode(Rating, movieID),
+			wantErr:  true,
+		},
+		{
+			name:     "Raw Database ID",
+			typeName: Movie,
+			globalID: movieID.String(),
+			wantErr:  true,
+		},
+		{
+			name:     "Not A UUID",
+			typeName: Movie,
+			globalID: base64.RawURLEncoding.EncodeToString([]byte("Movie:42")),
+			wantErr:  true,
+		},
+		{
+			name:     "Not Base64",
+			typeName: Movie,
+			globalID: "Movie:" + movieID.String(),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.typeName, tt.globalID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	userID := uuid.New()
+	globalID := Encode(User, userID)
+	assert.NotContains(t, globalID, userID.String())
+
+	typeName, id, err := Parse(globalID)
+	assert.NoError(t, err)
+	assert.Equal(t, User, typeName)
+	assert.Equal(t, userID, id)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// Reasons a sign-in can fail, passed to AuthError in the reason query parameter
+const (
+	authErrorExpired    = "expired"
+	authErrorDenied     = "denied"
+	authErrorUnverified = "unverified"
+	authErrorLink       = "link"
+	authErrorInUse      = "in_use"
+)
+
+var authErrorMessages = map[string]string{
+	authErrorExpired:    "Your sign-in took too long or was already used. Please start again.",
+	authErrorDenied:     "Signing in was cancelled on the provider's side.",
+	authErrorUnverified: "Your account has no verified email address, please verify it with the provider first.",
+	authErrorLink:       "This link has expired or was already used. Please request a new one.",
+	authErrorInUse:      "This provider account is already linked to another Next Watch account.",
+}
+
+type authErrorData struct {
+	Message  string
+	RetryURL string
+}
+
+var authErrorPage = template.Must(template.New("auth_error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Sign-in failed - Next Watch</title>
+  <style>
+    body { font-family: sans-serif; max-width: 32rem; margin: 6rem auto; text-align: center; color: #222; }
+    a { display: inline-block; margin-top: 1rem; padding: 0.5rem 1rem; border-radius: 4px; background: #222; color: #fff; text-decoration: none; }
+  </style>
+</head>
+<body>
+  <h1>Couldn't sign you in</h1>
+  <p>{{.Message}}</p>
+  <a href="{{.RetryURL}}">Try again</a>
+</body>
+</html>
+`))
+
+// AuthError explains why a sign-in failed and offers to start over
+func (h *Handler) AuthError(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	data := authErrorData{Message: authErrorMessages[query.Get("reason")], RetryURL: "/"}
+	if data.Message == "" {
+		data.Message = "Something went wrong while signing in."
+	}
+	if provider, ok := h.providers[query.Get("provider")]; ok {
+		data.RetryURL = "/auth/signin/" + provider.Name()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := authErrorPage.Execute(w, data); err != nil {
+		log.Printf("Failed to render sign-in error page: %v", err)
+	}
+}
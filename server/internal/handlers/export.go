@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/services"
+)
+
+// Export streams the signed in user's ratings as a Letterboxd compatible CSV (default) or as JSON
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		http.Error(w, "No user found", http.StatusUnauthorized)
+		return
+	}
+	if !auth.HasScope(ctx, models.ScopeRead) {
+		http.Error(w, "Access token lacks the read scope", http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="next-watch-ratings.csv"`)
+		err = h.exportService.WriteCSV(ctx, user, w)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="next-watch-export.json"`)
+		err = h.exportService.WriteJSON(ctx, user, w)
+	default:
+		http.Error(w, "Unsupported export format", http.StatusBadRequest)
+		return
+	}
+
+	// The response is already streaming at this point, so the error can only be logged
+	if err != nil {
+		log.Printf("Failed to export ratings of user %s: %v", user.ID, err)
+	}
+}
+
+// ExportArchive streams the archive behind a link from requestDataExport. The link is the credential,
+// so it works without being signed in, but only once.
+func (h *Handler) ExportArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := h.dataExportService.RedeemArchive(ctx, r.URL.Query().Get("token"))
+	if errors.Is(err, services.ErrInvalidDataExport) {
+		http.Error(w, "Export link is invalid, expired or was already used", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to redeem data export: %v", err)
+		http.Error(w, "Error preparing export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="next-watch-data.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.dataExportService.WriteArchive(ctx, user, w); err != nil {
+		log.Printf("Failed to export data of user %s: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/services"
+)
+
+// Local account endpoints take form values, so they work from a plain HTML form as well as from fetch
+
+// SignUp creates an email and password account and sends the verification email.
+// It answers the same whether or not the address already has an account.
+func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+
+	err := h.accountService.SignUp(r.Context(), r.FormValue("email"), r.FormValue("password"), r.FormValue("name"), clientIP(r))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// LocalSignin checks an email and password and starts a session
+func (h *Handler) LocalSignin(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+
+	ctx := r.Context()
+	user, err := h.accountService.SignIn(ctx, r.FormValue("email"), r.FormValue("password"), clientIP(r))
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, services.ErrEmailNotVerified):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		writeAccountError(w, err)
+		return
+	}
+	if user.SuspendedAt != nil {
+		http.Error(w, "Account suspended", http.StatusForbidden)
+		return
+	}
+
+	if err := h.startSession(w, r, user); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail redeems the link from the verification email
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	err := h.accountService.VerifyEmail(r.Context(), r.FormValue("token"))
+	if errors.Is(err, services.ErrInvalidAccountToken) {
+		http.Redirect(w, r, "/auth/error?reason="+authErrorLink, http.StatusFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to verify email: %v", err)
+		http.Error(w, "Error verifying email", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// RequestPasswordReset emails a reset link. It answers the same whether or not the address has an account.
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(r.Context(), r.FormValue("email"), clientIP(r)); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+var resetPasswordPage = template.Must(template.New("reset_password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Reset your password - Next Watch</title>
+  <style>
+    body { font-family: sans-serif; max-width: 24rem; margin: 6rem auto; text-align: center; color: #222; }
+    input { display: block; width: 100%; box-sizing: border-box; margin-top: 1rem; padding: 0.5rem; }
+    button { margin-top: 1rem; padding: 0.5rem 1rem; border: 0; border-radius: 4px; background: #222; color: #fff; }
+  </style>
+</head>
+<body>
+  <h1>Choose a new password</h1>
+  <form method="post" action="/auth/local/reset">
+    <input type="hidden" name="token" value="{{.}}">
+    <input type="password" name="password" placeholder="New password" minlength="8" maxlength="128" autocomplete="new-password" required>
+    <button type="submit">Reset password</button>
+  </form>
+</body>
+</html>
+`))
+
+// ResetPassword shows the form behind the link from the reset email on GET and sets the new password on POST.
+// Every session of the user is signed out, including the one an attacker may have.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if err := resetPasswordPage.Execute(w, r.FormValue("token")); err != nil {
+			log.Printf("Failed to render password reset page: %v", err)
+		}
+		return
+	}
+	if !requirePost(w, r) {
+		return
+	}
+
+	err := h.accountService.ResetPassword(r.Context(), r.FormValue("token"), r.FormValue("password"))
+	if errors.Is(err, services.ErrInvalidAccountToken) {
+		http.Redirect(w, r, "/auth/error?reason="+authErrorLink, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	h.clearSessionCookie(w)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// startSession signs the user in on this device by setting the session cookie
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	sessionToken, _, err := h.sessionService.StartSession(r.Context(), user, r.UserAgent(), clientIP(r))
+	if err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(h.sessionService.AbsoluteTimeout())
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionToken,
+		Expires:  expires,
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     signedInCookieName,
+		Value:    "1",
+		Expires:  expires,
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+		Secure:   h.secureCookies,
+	})
+	return nil
+}
+
+// writeAccountError answers rate limited requests with 429 and input the user has to correct with 400
+func writeAccountError(w http.ResponseWriter, err error) {
+	var rateLimitErr *services.RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(rateLimitErr.RetryAfter.Seconds())+1))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case apperr.CodeOf(err) == apperr.CodeValidation:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Failed to handle local account request: %v", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+	}
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
@@ -2,117 +2,367 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
+	"net"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/Azanul/Next-Watch/internal/auth"
 	"github.com/Azanul/Next-Watch/internal/models"
 	"github.com/Azanul/Next-Watch/internal/services"
+	"github.com/google/uuid"
 )
 
+// The session token cookie, scripts can't read it
+const sessionCookieName = "access_token"
+
+// Set next to the session cookie so the frontend can tell whether someone is signed in. It holds no credential.
+const signedInCookieName = "signed_in"
+
+// Ties a link started by LinkProvider to the browser that started it, holding its state
+const linkStateCookieName = "link_state"
+
 type Handler struct {
-	userService      *services.UserService
-	googleAuthClient *auth.GoogleAuthClient
+	userService        *services.UserService
+	sessionService     *services.SessionService
+	accessTokenService *services.AccessTokenService
+	exportService      *services.ExportService
+	dataExportService  *services.DataExportService
+	accountService     *services.AccountService
+	identityService    *services.IdentityService
+	providers          auth.ProviderRegistry
+	secureCookies      bool
 }
 
-func NewHandler(userService *services.UserService, googleAuthClient *auth.GoogleAuthClient) *Handler {
+func NewHandler(userService *services.UserService, sessionService *services.SessionService, accessTokenService *services.AccessTokenService, exportService *services.ExportService, dataExportService *services.DataExportService, accountService *services.AccountService, identityService *services.IdentityService, providers auth.ProviderRegistry) *Handler {
 	return &Handler{
-		userService:      userService,
-		googleAuthClient: googleAuthClient,
+		userService:        userService,
+		sessionService:     sessionService,
+		accessTokenService: accessTokenService,
+		exportService:      exportService,
+		dataExportService:  dataExportService,
+		accountService:     accountService,
+		identityService:    identityService,
+		providers:          providers,
 	}
 }
 
-// AuthMiddleware checks for a user in the request and adds it to the context
-func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		cookie, err := r.Cookie("access_token")
+// SetSecureCookies makes browsers only send the cookies of the handler over HTTPS
+func (h *Handler) SetSecureCookies(secure bool) {
+	h.secureCookies = secure
+}
+
+func (h *Handler) clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:   signedInCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+		Secure: h.secureCookies,
+	})
+}
+
+// authFailure is why credentials sent with a request were rejected
+type authFailure struct {
+	status  int
+	message string
+}
+
+// authenticate adds the user, and the session or access token they authenticated with, to the context.
+// Scripts authenticate with a personal access token in the Authorization header, browsers with the session cookie.
+// Requests without credentials get the context back unchanged.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (context.Context, *authFailure) {
+	ctx := r.Context()
+
+	var user *models.User
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		tokenUser, token, failure := h.authenticateAccessToken(ctx, authorization)
+		if failure != nil {
+			return ctx, failure
+		}
+		user = tokenUser
+		ctx = context.WithValue(ctx, "access_token", token)
+	} else {
+		cookie, err := r.Cookie(sessionCookieName)
 		if err != nil {
-			http.Error(w, "No access token found", http.StatusUnauthorized)
-			return
+			return ctx, nil
+		}
+		sessionUser, session, err := h.sessionService.Authenticate(ctx, cookie.Value)
+		if errors.Is(err, services.ErrInvalidSession) {
+			h.clearSessionCookie(w)
+			return ctx, &authFailure{http.StatusUnauthorized, "Session expired"}
 		}
-		claims, err := h.googleAuthClient.ValidateToken(cookie.Value)
 		if err != nil {
-			http.SetCookie(w, &http.Cookie{
-				Name:     "access_token",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
-			http.Error(w, "Invalid Google token", http.StatusUnauthorized)
-			return
+			return ctx, &authFailure{http.StatusInternalServerError, "Error getting session"}
 		}
+		user = sessionUser
+		ctx = context.WithValue(ctx, "session", session)
+	}
 
-		user, err := h.userService.GetUserByEmail(ctx, claims.Email)
-		if err != nil {
-			http.Error(w, "Error getting user", http.StatusInternalServerError)
+	if user.SuspendedAt != nil {
+		return r.Context(), &authFailure{http.StatusForbidden, "Account suspended"}
+	}
+
+	return context.WithValue(ctx, "user", user), nil
+}
+
+// authenticateAccessToken checks a personal access token sent as "Bearer <token>"
+func (h *Handler) authenticateAccessToken(ctx context.Context, authorization string) (*models.User, *models.PersonalAccessToken, *authFailure) {
+	bearerToken, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil, nil, &authFailure{http.StatusUnauthorized, "Unsupported authorization scheme"}
+	}
+	user, token, err := h.accessTokenService.Authenticate(ctx, strings.TrimSpace(bearerToken))
+	if errors.Is(err, services.ErrInvalidAccessToken) {
+		return nil, nil, &authFailure{http.StatusUnauthorized, "Invalid access token"}
+	}
+	if err != nil {
+		return nil, nil, &authFailure{http.StatusInternalServerError, "Error getting access token"}
+	}
+	return user, token, nil
+}
+
+// WebsocketInit authenticates a GraphQL websocket from its connection_init payload. Browsers are already
+// signed in by the session cookie of the upgrade request, scripts can't set headers on a websocket
+// and send "Authorization": "Bearer <token>" in the payload instead.
+func (h *Handler) WebsocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	authorization := payload.Authorization()
+	if authorization == "" {
+		return ctx, nil, nil
+	}
+	if _, err := auth.GetUserFromContext(ctx); err == nil {
+		return nil, nil, errors.New("the connection is already signed in")
+	}
+
+	user, token, failure := h.authenticateAccessToken(ctx, authorization)
+	if failure != nil {
+		return nil, nil, errors.New(failure.message)
+	}
+	if user.SuspendedAt != nil {
+		return nil, nil, errors.New("account suspended")
+	}
+	ctx = context.WithValue(ctx, "access_token", token)
+	return context.WithValue(ctx, "user", user), nil, nil
+}
+
+// AuthMiddleware only lets requests from signed in users through
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, failure := h.authenticate(w, r)
+		if failure != nil {
+			http.Error(w, failure.message, failure.status)
 			return
 		}
+		if _, err := auth.GetUserFromContext(ctx); err != nil {
+			http.Error(w, "No access token found", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		ctx = context.WithValue(ctx, "user", user)
+// OptionalAuthMiddleware adds the user to the context when the request is signed in and lets anonymous requests through.
+// Rejected credentials don't fail the request, the reason is kept for fields that require a user to report.
+func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, failure := h.authenticate(w, r)
+		if failure != nil {
+			if failure.status == http.StatusInternalServerError {
+				http.Error(w, failure.message, failure.status)
+				return
+			}
+			ctx = auth.WithAuthError(ctx, errors.New(failure.message))
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// Google sign in initiation handler
-func (h *Handler) GoogleSignin(w http.ResponseWriter, r *http.Request) {
-	authorizationURL, err := h.googleAuthClient.AuthorizationURL()
+// Signin sends the user to the sign in page of the provider in the path
+func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	authorizationURL, err := provider.AuthorizationURL(r.Context(), "")
 	if err != nil {
-		http.Error(w, "Error signing in with google", http.StatusInternalServerError)
+		http.Error(w, "Error signing in with "+provider.Name(), http.StatusInternalServerError)
 		return
 	}
 	http.Redirect(w, r, authorizationURL, http.StatusFound)
 }
 
-// Google OAuth callback handler
-func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+// LinkProvider sends the signed in user to the provider in the path to add it as another way to sign in
+func (h *Handler) LinkProvider(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	token, err := h.googleAuthClient.Callback(r.FormValue("code"), r.FormValue("state"))
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	user, err := auth.GetUserFromContext(ctx)
 	if err != nil {
-		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if _, err := auth.GetAccessTokenFromContext(ctx); err == nil {
+		http.Error(w, "Providers can only be linked when signed in through the browser", http.StatusForbidden)
 		return
 	}
 
-	claims, err := h.googleAuthClient.GetUserInfo(token.AccessToken)
+	authorizationURL, err := provider.AuthorizationURL(ctx, user.ID.String())
+	if err != nil {
+		http.Error(w, "Error linking "+provider.Name(), http.StatusInternalServerError)
+		return
+	}
+	parsed, err := url.Parse(authorizationURL)
 	if err != nil {
-		http.Error(w, "Failed to get token", http.StatusInternalServerError)
+		http.Error(w, "Error linking "+provider.Name(), http.StatusInternalServerError)
 		return
 	}
 
-	user, err := h.userService.GetUserByEmail(ctx, claims.Email)
+	// Otherwise someone could start a link to their own account and get another user to finish it with theirs.
+	// Lax, unlike the session cookie, so it is sent along when the provider redirects back.
+	http.SetCookie(w, &http.Cookie{
+		Name:     linkStateCookieName,
+		Value:    parsed.Query().Get("state"),
+		Path:     "/auth/callback/",
+		MaxAge:   int(auth.StateTTL.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, authorizationURL, http.StatusFound)
+}
+
+// Callback finishes a sign in with the provider in the path and starts a session
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	errorURL := func(reason string) string {
+		return "/auth/error?" + url.Values{"reason": {reason}, "provider": {provider.Name()}}.Encode()
+	}
+
+	// The user declined to share their account on the provider's consent screen
+	if r.FormValue("error") != "" {
+		http.Redirect(w, r, errorURL(authErrorDenied), http.StatusFound)
+		return
+	}
+
+	identity, signIn, err := provider.Callback(ctx, r.FormValue("code"), r.FormValue("state"))
+	if errors.Is(err, auth.ErrInvalidState) {
+		http.Redirect(w, r, errorURL(authErrorExpired), http.StatusFound)
+		return
+	}
 	if err != nil {
+		log.Printf("Failed to sign in with %s: %v", provider.Name(), err)
+		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
+		return
+	}
+	if signIn.LinkUserID != "" {
+		h.finishLink(w, r, identity, signIn.LinkUserID, errorURL)
+		return
+	}
+
+	user, err := h.identityService.SignIn(ctx, identity)
+	if errors.Is(err, services.ErrUnverifiedEmail) {
+		http.Redirect(w, r, errorURL(authErrorUnverified), http.StatusFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to sign in with %s: %v", provider.Name(), err)
 		http.Error(w, "Error getting user", http.StatusInternalServerError)
 		return
 	}
-	if user == nil {
-		user = &models.User{
-			Email: claims.Email,
-			Name:  claims.Name,
-		}
-		err := h.userService.CreateUser(ctx, user)
-		if err != nil {
-			http.Error(w, "Error creating user", http.StatusInternalServerError)
+
+	if err := h.startSession(w, r, user); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// Logout revokes the current session and clears its cookie. Only POST is accepted, so other sites can't
+// sign users out with a link or an image.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := h.sessionService.EndSession(r.Context(), cookie.Value); err != nil {
+			log.Printf("Failed to end session: %v", err)
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
 			return
 		}
 	}
+	h.clearSessionCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	encryptedToken, err := auth.EncryptToken(token.AccessToken)
-	if err != nil {
-		http.Error(w, "Failed to encrypt token", http.StatusInternalServerError)
+// finishLink adds the provider account to the user who started linking it in this browser
+func (h *Handler) finishLink(w http.ResponseWriter, r *http.Request, identity *auth.Identity, linkUserID string, errorURL func(string) string) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie(linkStateCookieName)
+	http.SetCookie(w, &http.Cookie{Name: linkStateCookieName, Path: "/auth/callback/", MaxAge: -1, Secure: h.secureCookies, HttpOnly: true})
+	if err != nil || cookie.Value != r.FormValue("state") {
+		http.Redirect(w, r, errorURL(authErrorExpired), http.StatusFound)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    encryptedToken,
-		Expires:  time.Now().Add(3600 * 24 * 7 * time.Second), // 1 week
-		Path:     "/",
-		SameSite: http.SameSiteStrictMode,
-		Secure:   false, // Set to true in production
-		HttpOnly: false, // Set to true in production
-	})
+	userID, err := uuid.Parse(linkUserID)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+	user, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		http.Error(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.SuspendedAt != nil {
+		http.Redirect(w, r, errorURL(authErrorExpired), http.StatusFound)
+		return
+	}
 
+	err = h.identityService.Link(ctx, user, identity)
+	if errors.Is(err, services.ErrIdentityInUse) {
+		http.Redirect(w, r, errorURL(authErrorInUse), http.StatusFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to link %s: %v", identity.Provider, err)
+		http.Error(w, "Error linking provider", http.StatusInternalServerError)
+		return
+	}
 	http.Redirect(w, r, "/", http.StatusFound)
 }
+
+// clientIP is the address the request came from, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "Next Watch <noreply@example.com>")
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{
+		To:      "test@example.com",
+		Subject: "Verify your email\r\nBcc: victim@example.com",
+		Body:    "Open this link:\nhttps://example.com/verify",
+	})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: test@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Verify your emailBcc: victim@example.com\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nOpen this link:\r\nhttps://example.com/verify")
+}
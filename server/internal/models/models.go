@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,12 +21,24 @@ type Movie struct {
 }
 
 type User struct {
-	ID        uuid.UUID       `json:"id"`
-	Email     string          `json:"email"`
-	Name      string          `json:"name"`
-	Role      string          `json:"role"`
-	Taste     pgvector.Vector `json:"-"`
-	CreatedAt time.Time       `json:"createdAt"`
+	ID              uuid.UUID       `json:"id"`
+	Email           string          `json:"email"`
+	Name            string          `json:"name"`
+	AvatarURL       string          `json:"avatarUrl"`
+	Role            string          `json:"role"`
+	Taste           pgvector.Vector `json:"-"`
+	ScoreStats      ScoreStats      `json:"-"`
+	PasswordHash    string          `json:"-"` // Empty for users who only sign in with a provider
+	EmailVerifiedAt *time.Time      `json:"emailVerifiedAt,omitempty"`
+	SuspendedAt     *time.Time      `json:"suspendedAt,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+}
+
+// ScoreStats are the running mean and variance of a user's scores (Welford's algorithm)
+type ScoreStats struct {
+	Count int
+	Mean  float64
+	M2    float64 // Sum of squared differences from the mean
 }
 
 type Rating struct {
@@ -36,3 +49,81 @@ type Rating struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+// Session is a signed in browser or device. Only a hash of its token is stored.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"userId"`
+	TokenHash  []byte     `json:"-"`
+	UserAgent  string     `json:"userAgent"`
+	IPAddress  string     `json:"ipAddress"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Scopes of a personal access token
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// PersonalAccessToken lets scripts call the API as a user. Only a hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"userId"`
+	Name       string     `json:"name"`
+	TokenHash  []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// DataExport is a one-time link to download an archive of everything stored about a user.
+// Only a hash of its token is stored.
+type DataExport struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"userId"`
+	TokenHash    []byte     `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	DownloadedAt *time.Time `json:"downloadedAt,omitempty"`
+}
+
+// Purposes of an account token
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// AccountToken is a one-time link sent by email to verify an address or reset a password.
+// Only a hash of its token is stored.
+type AccountToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"userId"`
+	Purpose   string     `json:"purpose"`
+	TokenHash []byte     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// UserIdentity is a provider account linked to a user. Sign-ins are matched on the provider's
+// Subject, which never changes, so a user who changes their email on the provider keeps their account.
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"userId"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"` // As the provider last reported it
+	CreatedAt time.Time `json:"createdAt"`
+	// Sealed with the keyring, empty unless the provider granted offline access
+	RefreshToken string `json:"-"`
+}
@@ -0,0 +1,76 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errNotInList = "PERSISTED_QUERY_NOT_IN_LIST"
+
+// Registry is where Allowlist looks up the queries registered ahead of time
+type Registry interface {
+	// Registered returns the registered query with the given hash
+	Registered(ctx context.Context, hash string) (query string, ok bool, err error)
+}
+
+// Allowlist is a gqlgen extension that only runs registered queries, it replaces
+// extension.AutomaticPersistedQuery. Clients send the hash of a query the same way they do for APQ,
+// but an unknown hash is refused instead of asking for the full query, and a request with
+// nothing but a query is refused too.
+type Allowlist struct {
+	Registry Registry
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = Allowlist{}
+
+func (a Allowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+func (a Allowlist) Validate(schema graphql.ExecutableSchema) error {
+	if a.Registry == nil {
+		return errors.New("the allowlist needs a registry")
+	}
+	return nil
+}
+
+func (a Allowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	extension, _ := rawParams.Extensions["persistedQuery"].(map[string]interface{})
+	hash, _ := extension["sha256Hash"].(string)
+	if hash == "" {
+		return notInList("only registered queries are accepted, send the hash of one as a persisted query")
+	}
+	// The version is a json.Number or a float64 depending on the transport
+	if version := fmt.Sprint(extension["version"]); version != "1" {
+		return gqlerror.Errorf("unsupported persisted query version %s", version)
+	}
+
+	query, ok, err := a.Registry.Registered(ctx, hash)
+	if err != nil {
+		log.Printf("Failed to look up registered query: %v", err)
+		return gqlerror.Errorf("failed to look up the persisted query")
+	}
+	if !ok {
+		return notInList("the persisted query is not registered")
+	}
+	if rawParams.Query != "" && rawParams.Query != query {
+		return gqlerror.Errorf("provided persisted query hash does not match the query")
+	}
+	rawParams.Query = query
+	return nil
+}
+
+func notInList(message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Extensions: map[string]interface{}{"code": errNotInList},
+	}
+}
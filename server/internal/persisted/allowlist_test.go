@@ -0,0 +1,117 @@
+package persisted
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type mapRegistry map[string]string
+
+func (r mapRegistry) Registered(ctx context.Context, hash string) (string, bool, error) {
+	query, ok := r[hash]
+	return query, ok, nil
+}
+
+func TestAllowlist(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query { movies: [Movie!]! }
+		type Movie { id: ID! }
+	`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			return graphql.OneShot(&graphql.Response{Data: []byte(`{"movies":[]}`)})
+		},
+	})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(Allowlist{Registry: mapRegistry{Hash(testQuery): testQuery}})
+
+	persistedQuery := func(hash string) map[string]interface{} {
+		return map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash}}
+	}
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		wantCode string
+		wantErr  bool
+	}{
+		{
+			name: "Registered Hash",
+			body: map[string]interface{}{"extensions": persistedQuery(Hash(testQuery))},
+		},
+		{
+			name: "Registered Hash With Its Query",
+			body: map[string]interface{}{"query": testQuery, "extensions": persistedQuery(Hash(testQuery))},
+		},
+		{
+			name:     "Unknown Hash",
+			body:     map[string]interface{}{"extensions": persistedQuery(Hash("{ movies { id } }"))},
+			wantCode: errNotInList,
+			wantErr:  true,
+		},
+		{
+			name:     "Query Without Hash",
+			body:     map[string]interface{}{"query": testQuery},
+			wantCode: errNotInList,
+			wantErr:  true,
+		},
+		{
+			name:    "Registered Hash With Another Query",
+			body:    map[string]interface{}{"query": "{ movies { id } }", "extensions": persistedQuery(Hash(testQuery))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+			r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+
+			var resp struct {
+				Errors []struct {
+					Extensions map[string]interface{} `json:"extensions"`
+				} `json:"errors"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if !tt.wantErr {
+				assert.Empty(t, resp.Errors)
+				return
+			}
+			require.NotEmpty(t, resp.Errors)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, resp.Errors[0].Extensions["code"])
+			}
+		})
+	}
+
+	t.Run("Hash Sent With GET", func(t *testing.T) {
+		extensions, err := json.Marshal(persistedQuery(Hash(testQuery)))
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodGet, "/query?extensions="+url.QueryEscape(string(extensions)), nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		assert.JSONEq(t, `{"data":{"movies":[]}}`, w.Body.String())
+	})
+}
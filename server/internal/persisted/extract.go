@@ -0,0 +1,22 @@
+package persisted
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var gqlTemplate = regexp.MustCompile("(?s)gql`(.*?)`")
+
+// ExtractQueries returns the GraphQL documents written as gql`...` templates in JavaScript or TypeScript source.
+// Templates that interpolate with ${...}, usually to include fragments, can't be read without running the code.
+func ExtractQueries(source string) ([]string, error) {
+	var queries []string
+	for _, match := range gqlTemplate.FindAllStringSubmatch(source, -1) {
+		if strings.Contains(match[1], "${") {
+			return nil, errors.New("gql templates with ${...} interpolations are not supported")
+		}
+		queries = append(queries, match[1])
+	}
+	return queries, nil
+}
@@ -0,0 +1,256 @@
+package persisted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// graphql-js breaks the arguments of a field over several lines past this length
+const maxLineLength = 80
+
+// Hash is the key of a query in the store, the hex encoded SHA-256 of its text as clients send it
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Print formats a document the way graphql-js's print does. Apollo Client hashes the printed
+// document, so a query registered from the frontend's source must be printed the same way to
+// get the hash the browser will send.
+//
+// Two things can't be told apart after gqlparser parsed the document and print differently than
+// in graphql-js: an alias equal to the field name, which is dropped, and block strings, which
+// are printed as regular strings.
+func Print(doc *ast.QueryDocument) string {
+	type definition struct {
+		start int
+		text  string
+	}
+	var definitions []definition
+	for _, op := range doc.Operations {
+		definitions = append(definitions, definition{start: positionStart(op.Position), text: printOperation(op)})
+	}
+	for _, fragment := range doc.Fragments {
+		definitions = append(definitions, definition{start: positionStart(fragment.Position), text: printFragment(fragment)})
+	}
+	// gqlparser keeps operations and fragments apart, the source order is restored from their positions
+	sort.SliceStable(definitions, func(i, j int) bool { return definitions[i].start < definitions[j].start })
+
+	texts := make([]string, len(definitions))
+	for i, d := range definitions {
+		texts[i] = d.text
+	}
+	return join(texts, "\n\n")
+}
+
+// AddTypename adds __typename to every selection set but the root of operations, like Apollo Client
+// does before sending a query, unless the selection set already asks for a field starting with __
+func AddTypename(doc *ast.QueryDocument) {
+	for _, op := range doc.Operations {
+		for _, selection := range op.SelectionSet {
+			addTypename(selection)
+		}
+	}
+	for _, fragment := range doc.Fragments {
+		fragment.SelectionSet = withTypename(fragment.SelectionSet)
+	}
+}
+
+func addTypename(selection ast.Selection) {
+	switch s := selection.(type) {
+	case *ast.Field:
+		s.SelectionSet = withTypename(s.SelectionSet)
+	case *ast.InlineFragment:
+		s.SelectionSet = withTypename(s.SelectionSet)
+	}
+}
+
+func withTypename(set ast.SelectionSet) ast.SelectionSet {
+	if len(set) == 0 {
+		return set
+	}
+	for _, selection := range set {
+		addTypename(selection)
+	}
+	for _, selection := range set {
+		if field, ok := selection.(*ast.Field); ok && strings.HasPrefix(field.Name, "__") {
+			return set
+		}
+	}
+	return append(set, &ast.Field{Alias: "__typename", Name: "__typename"})
+}
+
+func printOperation(op *ast.OperationDefinition) string {
+	variables := make([]string, len(op.VariableDefinitions))
+	for i, v := range op.VariableDefinitions {
+		variables[i] = printVariableDefinition(v)
+	}
+
+	prefix := join([]string{
+		string(op.Operation),
+		op.Name + wrap("(", join(variables, ", "), ")"),
+		printDirectives(op.Directives),
+	}, " ")
+	// Anonymous queries without variables or directives use the short form
+	if prefix == "query" {
+		return printSelectionSet(op.SelectionSet)
+	}
+	return prefix + " " + printSelectionSet(op.SelectionSet)
+}
+
+func printFragment(fragment *ast.FragmentDefinition) string {
+	variables := make([]string, len(fragment.VariableDefinition))
+	for i, v := range fragment.VariableDefinition {
+		variables[i] = printVariableDefinition(v)
+	}
+	return "fragment " + fragment.Name + wrap("(", join(variables, ", "), ")") +
+		" on " + fragment.TypeCondition + " " + wrap("", printDirectives(fragment.Directives), " ") +
+		printSelectionSet(fragment.SelectionSet)
+}
+
+func printVariableDefinition(v *ast.VariableDefinition) string {
+	text := "$" + v.Variable + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		text += " = " + printValue(v.DefaultValue)
+	}
+	return text + wrap(" ", printDirectives(v.Directives), "")
+}
+
+func printSelectionSet(set ast.SelectionSet) string {
+	selections := make([]string, len(set))
+	for i, selection := range set {
+		selections[i] = printSelection(selection)
+	}
+	return block(selections)
+}
+
+func printSelection(selection ast.Selection) string {
+	switch s := selection.(type) {
+	case *ast.Field:
+		prefix := s.Name
+		if s.Alias != "" && s.Alias != s.Name {
+			prefix = s.Alias + ": " + s.Name
+		}
+		arguments := printArguments(s.Arguments)
+		line := prefix + wrap("(", join(arguments, ", "), ")")
+		if len(line) > maxLineLength {
+			line = prefix + wrap("(\n", indent(join(arguments, "\n")), "\n)")
+		}
+		return join([]string{line, printDirectives(s.Directives), printSelectionSet(s.SelectionSet)}, " ")
+	case *ast.FragmentSpread:
+		return "..." + s.Name + wrap(" ", printDirectives(s.Directives), "")
+	case *ast.InlineFragment:
+		return join([]string{"...", wrap("on ", s.TypeCondition, ""), printDirectives(s.Directives), printSelectionSet(s.SelectionSet)}, " ")
+	default:
+		panic(fmt.Sprintf("unexpected selection %T", selection))
+	}
+}
+
+func printArguments(arguments ast.ArgumentList) []string {
+	texts := make([]string, len(arguments))
+	for i, argument := range arguments {
+		texts[i] = argument.Name + ": " + printValue(argument.Value)
+	}
+	return texts
+}
+
+func printDirectives(directives ast.DirectiveList) string {
+	texts := make([]string, len(directives))
+	for i, directive := range directives {
+		texts[i] = "@" + directive.Name + wrap("(", join(printArguments(directive.Arguments), ", "), ")")
+	}
+	return join(texts, " ")
+}
+
+func printValue(value *ast.Value) string {
+	switch value.Kind {
+	case ast.Variable:
+		return "$" + value.Raw
+	case ast.StringValue, ast.BlockValue:
+		return printString(value.Raw)
+	case ast.ListValue:
+		items := make([]string, len(value.Children))
+		for i, child := range value.Children {
+			items[i] = printValue(child.Value)
+		}
+		return "[" + join(items, ", ") + "]"
+	case ast.ObjectValue:
+		fields := make([]string, len(value.Children))
+		for i, child := range value.Children {
+			fields[i] = child.Name + ": " + printValue(child.Value)
+		}
+		return "{" + join(fields, ", ") + "}"
+	default:
+		// Ints, floats, booleans, null and enums are printed as written
+		return value.Raw
+	}
+}
+
+// printString quotes a string, escaping quotes, backslashes and control characters like graphql-js
+func printString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || (r >= 0x7f && r <= 0x9f):
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// The helpers below are those of graphql-js's printer, which skip empty parts
+
+func join(parts []string, separator string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, separator)
+}
+
+func wrap(start, s, end string) string {
+	if s == "" {
+		return ""
+	}
+	return start + s + end
+}
+
+func block(lines []string) string {
+	return wrap("{\n", indent(join(lines, "\n")), "\n}")
+}
+
+func indent(s string) string {
+	return wrap("  ", strings.ReplaceAll(s, "\n", "\n  "), "")
+}
+
+func positionStart(position *ast.Position) int {
+	if position == nil {
+		return 0
+	}
+	return position.Start
+}
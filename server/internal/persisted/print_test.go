@@ -0,0 +1,120 @@
+package persisted
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		typename bool
+		want     string
+	}{
+		{
+			name:   "Anonymous Query",
+			source: `{ movies { id } }`,
+			want:   "{\n  movies {\n    id\n  }\n}",
+		},
+		{
+			name: "Operation With Variables",
+			source: `
+				query GetMovies($page: Int!, $pageSize: Int! = 10, $genres: [String!]) {
+					movies(page: $page, pageSize: $pageSize) { edges { node { id title } } totalCount }
+				}`,
+			want: "query GetMovies($page: Int!, $pageSize: Int! = 10, $genres: [String!]) {\n" +
+				"  movies(page: $page, pageSize: $pageSize) {\n" +
+				"    edges {\n" +
+				"      node {\n" +
+				"        id\n" +
+				"        title\n" +
+				"      }\n" +
+				"    }\n" +
+				"    totalCount\n" +
+				"  }\n" +
+				"}",
+		},
+		{
+			name:     "Adds Typename Below The Root",
+			source:   `mutation RateMovie($id: ID!) { rateMovie(movieId: $id, score: 4.5) { id movie { title } } }`,
+			typename: true,
+			want: "mutation RateMovie($id: ID!) {\n" +
+				"  rateMovie(movieId: $id, score: 4.5) {\n" +
+				"    id\n" +
+				"    movie {\n" +
+				"      title\n" +
+				"      __typename\n" +
+				"    }\n" +
+				"    __typename\n" +
+				"  }\n" +
+				"}",
+		},
+		{
+			name:     "Keeps Existing Typename",
+			source:   `{ movie { __typename id } }`,
+			typename: true,
+			want:     "{\n  movie {\n    __typename\n    id\n  }\n}",
+		},
+		{
+			name: "Fragments In Source Order",
+			source: `
+				fragment MovieFields on Movie @deprecated { id ... on Movie { title } }
+				query Q { movie { ...MovieFields @skip(if: false) aliased: title } }`,
+			typename: true,
+			want: "fragment MovieFields on Movie @deprecated {\n" +
+				"  id\n" +
+				"  ... on Movie {\n" +
+				"    title\n" +
+				"    __typename\n" +
+				"  }\n" +
+				"  __typename\n" +
+				"}\n" +
+				"\n" +
+				"query Q {\n" +
+				"  movie {\n" +
+				"    ...MovieFields @skip(if: false)\n" +
+				"    aliased: title\n" +
+				"    __typename\n" +
+				"  }\n" +
+				"}",
+		},
+		{
+			name:   "Breaks Long Arguments And Escapes Strings",
+			source: `{ search(query: "say \"hi\"\n\\", filter: {genre: DRAMA, years: [1999, 2000]}, first: 100, after: null) }`,
+			want: "{\n" +
+				"  search(\n" +
+				"    query: \"say \\\"hi\\\"\\n\\\\\"\n" +
+				"    filter: {genre: DRAMA, years: [1999, 2000]}\n" +
+				"    first: 100\n" +
+				"    after: null\n" +
+				"  )\n" +
+				"}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.ParseQuery(&ast.Source{Input: tt.source})
+			require.NoError(t, err)
+			if tt.typename {
+				AddTypename(doc)
+			}
+			assert.Equal(t, tt.want, Print(doc))
+		})
+	}
+}
+
+func TestExtractQueries(t *testing.T) {
+	queries, err := ExtractQueries("import { gql } from '@apollo/client';\n\n" +
+		"export const A = gql`\n  query A { a }\n`;\n\nexport const B = gql`query B { b }`;\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"\n  query A { a }\n", "query B { b }"}, queries)
+
+	_, err = ExtractQueries("const A = gql`query A { ...F }\n${FRAGMENT}`;")
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+// Package persisted keeps the queries of automatic persisted queries (APQ), shares the queries registered
+// ahead of time between replicas, and can restrict the API to those
+package persisted
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+)
+
+// DefaultCacheSize is how many queries a PostgresStore keeps in process
+const DefaultCacheSize = 1000
+
+// MaxQueryLength is the longest query a client can persist. Longer queries still run, they are just
+// sent in full every time.
+const MaxQueryLength = 16 << 10
+
+type storedQuery struct {
+	query      string
+	registered bool
+}
+
+// PostgresStore keeps the registered queries in the persisted_queries table, so any replica can serve them
+// by their hash. Queries persisted by clients only live in the in-process cache with the queries last looked
+// up, which is bounded, so clients can't grow the table.
+type PostgresStore struct {
+	db    *sql.DB
+	cache *lru.LRU[storedQuery]
+}
+
+// Checking if PostgresStore can be the cache of extension.AutomaticPersistedQuery and of Allowlist during compile time
+var (
+	_ graphql.Cache[string] = (*PostgresStore)(nil)
+	_ Registry              = (*PostgresStore)(nil)
+)
+
+func NewPostgresStore(db *sql.DB, cacheSize int) *PostgresStore {
+	return &PostgresStore{db: db, cache: lru.New[storedQuery](cacheSize)}
+}
+
+// Get returns the query with the given hash. Errors are logged and reported as a missing query,
+// the client then sends the full query again.
+func (s *PostgresStore) Get(ctx context.Context, hash string) (string, bool) {
+	if stored, ok := s.cache.Get(ctx, hash); ok {
+		return stored.query, true
+	}
+	stored, ok, err := s.load(ctx, hash)
+	if err != nil {
+		log.Printf("Failed to look up persisted query: %v", err)
+		return "", false
+	}
+	return stored.query, ok
+}
+
+// Add keeps a query sent by a client in process. The APQ extension already checked that hash is the query's hash.
+func (s *PostgresStore) Add(ctx context.Context, hash string, query string) {
+	if len(query) > MaxQueryLength {
+		return
+	}
+	// Don't replace a registered query, Registered would have to look it up again
+	if _, ok := s.cache.Get(ctx, hash); ok {
+		return
+	}
+	s.cache.Add(ctx, hash, storedQuery{query: query})
+}
+
+// Register adds a query to the allowlist and returns its hash
+func (s *PostgresStore) Register(ctx context.Context, query string) (string, error) {
+	hash := Hash(query)
+	insert := `INSERT INTO persisted_queries (hash, query, registered)
+               VALUES ($1, $2, TRUE)
+               ON CONFLICT (hash) DO UPDATE SET registered = TRUE`
+
+	if _, err := s.db.ExecContext(ctx, insert, hash, query); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Registered returns the query with the given hash if it was registered
+func (s *PostgresStore) Registered(ctx context.Context, hash string) (string, bool, error) {
+	// A query cached before it was registered is looked up again, registering doesn't reach the cache of running replicas
+	if stored, ok := s.cache.Get(ctx, hash); ok && stored.registered {
+		return stored.query, true, nil
+	}
+	stored, ok, err := s.load(ctx, hash)
+	if err != nil || !ok || !stored.registered {
+		return "", false, err
+	}
+	return stored.query, true, nil
+}
+
+func (s *PostgresStore) load(ctx context.Context, hash string) (storedQuery, bool, error) {
+	query := `SELECT query, registered FROM persisted_queries
+              WHERE hash = $1`
+
+	var stored storedQuery
+	err := s.db.QueryRowContext(ctx, query, hash).Scan(&stored.query, &stored.registered)
+	if err == sql.ErrNoRows {
+		return storedQuery{}, false, nil
+	}
+	if err != nil {
+		return storedQuery{}, false, err
+	}
+	s.cache.Add(ctx, hash, stored)
+	return stored, true, nil
+}
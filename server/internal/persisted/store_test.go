@@ -0,0 +1,146 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testQuery = "{\n  movies {\n    id\n  }\n}"
+
+var queryRowColumns = []string{"query", "registered"}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	hash := Hash(testQuery)
+
+	t.Run("Get caches queries in process", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows(queryRowColumns).AddRow(testQuery, false))
+
+		for i := 0; i < 2; i++ {
+			query, ok := store.Get(ctx, hash)
+			assert.True(t, ok)
+			assert.Equal(t, testQuery, query)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Get reports errors as missing queries", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(hash).
+			WillReturnError(errors.New("connection refused"))
+
+		_, ok := store.Get(ctx, hash)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Add keeps client queries in process only", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		store.Add(ctx, hash, testQuery)
+		query, ok := store.Get(ctx, hash)
+		assert.True(t, ok)
+		assert.Equal(t, testQuery, query)
+
+		// A client query isn't registered
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows(queryRowColumns))
+		_, ok, err := store.Registered(ctx, hash)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Add ignores long queries", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		longQuery := "{ movies { " + strings.Repeat("id ", MaxQueryLength) + "} }"
+		longHash := Hash(longQuery)
+		store.Add(ctx, longHash, longQuery)
+
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(longHash).
+			WillReturnRows(sqlmock.NewRows(queryRowColumns))
+		_, ok := store.Get(ctx, longHash)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Register marks the query as registered", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		mock.ExpectExec("INSERT INTO persisted_queries .+ DO UPDATE SET registered = TRUE").
+			WithArgs(hash, testQuery).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		got, err := store.Register(ctx, testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Registered looks up queries cached before they were registered again", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows(queryRowColumns).AddRow(testQuery, false))
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows(queryRowColumns).AddRow(testQuery, true))
+
+		_, ok, err := store.Registered(ctx, hash)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		query, ok, err := store.Registered(ctx, hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, testQuery, query)
+
+		// Registered queries are served from the cache
+		_, ok, err = store.Registered(ctx, hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Registered unknown hash", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		store := NewPostgresStore(db, 10)
+
+		mock.ExpectQuery("SELECT query, registered FROM persisted_queries").
+			WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows(queryRowColumns))
+
+		_, ok, err := store.Registered(ctx, "unknown")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
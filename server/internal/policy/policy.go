@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+)
+
+type Role string
+
+// Only the built-in roles are constants, any others come from the roles file
+const (
+	RoleUser  Role = "USER"
+	RoleAdmin Role = "ADMIN"
+)
+
+type Permission string
+
+const (
+	// CatalogWrite allows creating, editing and deleting movies
+	CatalogWrite Permission = "catalog:write"
+	// RatingsModerate allows deleting other users' ratings
+	RatingsModerate Permission = "ratings:moderate"
+	// UsersAdmin allows managing users and seeing their private fields
+	UsersAdmin Permission = "users:admin"
+)
+
+var allPermissions = map[Permission]bool{
+	CatalogWrite:    true,
+	RatingsModerate: true,
+	UsersAdmin:      true,
+}
+
+// DefaultRoles is the permission table used when no roles file is configured.
+// Other roles, e.g. CURATOR with catalog:write, are enabled through the roles file.
+var DefaultRoles = map[Role][]Permission{
+	RoleUser:  {},
+	RoleAdmin: {CatalogWrite, RatingsModerate, UsersAdmin},
+}
+
+// Policy decides what a user may do based on the permissions granted to their role
+type Policy struct {
+	roles map[Role]map[Permission]bool
+}
+
+// New builds a policy from a role to permissions table, rejecting unknown permissions
+func New(roles map[Role][]Permission) (*Policy, error) {
+	p := &Policy{roles: make(map[Role]map[Permission]bool, len(roles))}
+	for role, permissions := range roles {
+		if role == "" {
+			return nil, fmt.Errorf("role name cannot be empty")
+		}
+		p.roles[role] = make(map[Permission]bool, len(permissions))
+		for _, permission := range permissions {
+			if !allPermissions[permission] {
+				return nil, fmt.Errorf("role %s has unknown permission %q", role, permission)
+			}
+			p.roles[role][permission] = true
+		}
+	}
+	if _, ok := p.roles[RoleUser]; !ok {
+		return nil, fmt.Errorf("role %s must be defined, it is given to every new user", RoleUser)
+	}
+	return p, nil
+}
+
+// Default returns the policy built from DefaultRoles
+func Default() *Policy {
+	p, err := New(DefaultRoles)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Load reads a JSON file mapping role names to permission lists, e.g. {"CURATOR": ["catalog:write"]}.
+// Roles in the file replace or extend DefaultRoles. An empty path returns the default policy.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roles file: %w", err)
+	}
+	var overrides map[Role][]Permission
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse roles file: %w", err)
+	}
+
+	roles := make(map[Role][]Permission, len(DefaultRoles)+len(overrides))
+	for role, permissions := range DefaultRoles {
+		roles[role] = permissions
+	}
+	for role, permissions := range overrides {
+		roles[role] = permissions
+	}
+	return New(roles)
+}
+
+// IsPermission reports whether name is a permission the application knows about
+func IsPermission(name string) bool {
+	return allPermissions[Permission(name)]
+}
+
+// HasRole reports whether role is defined in the policy
+func (p *Policy) HasRole(role Role) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+// Roles returns every defined role, sorted by name
+func (p *Policy) Roles() []Role {
+	roles := make([]Role, 0, len(p.roles))
+	for role := range p.roles {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// Can reports whether the user's role grants the permission
+func (p *Policy) Can(user *models.User, permission Permission) bool {
+	if user == nil {
+		return false
+	}
+	return p.roles[Role(user.Role)][permission]
+}
+
+// CanViewPrivateFields reports whether viewer may see the private profile fields of user
+func (p *Policy) CanViewPrivateFields(viewer, user *models.User) bool {
+	if viewer == nil || user == nil {
+		return false
+	}
+	return viewer.ID == user.ID || p.Can(viewer, UsersAdmin)
+}
+
+// CanDeleteRating reports whether user may delete the rating
+func (p *Policy) CanDeleteRating(user *models.User, rating *models.Rating) bool {
+	if user == nil || rating == nil {
+		return false
+	}
+	return rating.UserID == user.ID || p.Can(user, RatingsModerate)
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Can(t *testing.T) {
+	p, err := New(map[Role][]Permission{
+		RoleUser:        {},
+		Role("CURATOR"): {CatalogWrite},
+		RoleAdmin:       {CatalogWrite, RatingsModerate, UsersAdmin},
+	})
+	assert.NoError(t, err)
+
+	user := &models.User{ID: uuid.New(), Role: "USER"}
+	curator := &models.User{ID: uuid.New(), Role: "CURATOR"}
+	admin := &models.User{ID: uuid.New(), Role: "ADMIN"}
+	unknown := &models.User{ID: uuid.New(), Role: "GHOST"}
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		permission Permission
+		want       bool
+	}{
+		{name: "User cannot write catalog", user: user, permission: CatalogWrite, want: false},
+		{name: "Curator can write catalog", user: curator, permission: CatalogWrite, want: true},
+		{name: "Curator cannot moderate ratings", user: curator, permission: RatingsModerate, want: false},
+		{name: "Admin can administer users", user: admin, permission: UsersAdmin, want: true},
+		{name: "Unknown role has no permissions", user: unknown, permission: CatalogWrite, want: false},
+		{name: "Anonymous has no permissions", user: nil, permission: CatalogWrite, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Can(tt.user, tt.permission))
+		})
+	}
+}
+
+func TestPolicy_Ownership(t *testing.T) {
+	p := Default()
+	owner := &models.User{ID: uuid.New(), Role: "USER"}
+	other := &models.User{ID: uuid.New(), Role: "USER"}
+	admin := &models.User{ID: uuid.New(), Role: "ADMIN"}
+	rating := &models.Rating{ID: uuid.New(), UserID: owner.ID}
+
+	assert.True(t, p.CanViewPrivateFields(owner, owner))
+	assert.True(t, p.CanViewPrivateFields(admin, owner))
+	assert.False(t, p.CanViewPrivateFields(other, owner))
+	assert.False(t, p.CanViewPrivateFields(nil, owner))
+
+	assert.True(t, p.CanDeleteRating(owner, rating))
+	assert.True(t, p.CanDeleteRating(admin, rating))
+	assert.False(t, p.CanDeleteRating(other, rating))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(map[Role][]Permission{RoleUser: {"catalog:delete"}})
+	assert.Error(t, err)
+
+	_, err = New(map[Role][]Permission{RoleAdmin: {UsersAdmin}})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Empty path returns defaults", func(t *testing.T) {
+		p, err := Load("")
+		assert.NoError(t, err)
+		assert.Equal(t, []Role{RoleAdmin, RoleUser}, p.Roles())
+		assert.False(t, p.HasRole(Role("CURATOR")))
+	})
+
+	t.Run("File adds and overrides roles", func(t *testing.T) {
+		path := filepath.Join(dir, "roles.json")
+		err := os.WriteFile(path, []byte(`{"CURATOR": ["catalog:write"], "USER": ["ratings:moderate"]}`), 0600)
+		assert.NoError(t, err)
+
+		p, err := Load(path)
+		assert.NoError(t, err)
+		assert.True(t, p.HasRole(Role("CURATOR")))
+		assert.True(t, p.Can(&models.User{Role: "CURATOR"}, CatalogWrite))
+		assert.False(t, p.Can(&models.User{Role: "CURATOR"}, UsersAdmin))
+		assert.True(t, p.Can(&models.User{Role: "USER"}, RatingsModerate))
+		assert.True(t, p.Can(&models.User{Role: "ADMIN"}, UsersAdmin))
+	})
+
+	t.Run("Unknown permission is rejected", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		err := os.WriteFile(path, []byte(`{"CURATOR": ["catalog:erase"]}`), 0600)
+		assert.NoError(t, err)
+
+		_, err = Load(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := Load(filepath.Join(dir, "missing.json"))
+		assert.Error(t, err)
+	})
+}
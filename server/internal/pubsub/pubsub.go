@@ -0,0 +1,69 @@
+// Package pubsub passes events between parts of the server, e.g. from the mutation that rated a movie to the
+// subscriptions waiting for it. Payloads are strings so Postgres LISTEN/NOTIFY can carry them once events
+// have to reach the subscribers of other replicas.
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// How many events a subscriber may fall behind before further events to it are dropped
+const subscriberBuffer = 16
+
+type PubSub interface {
+	Publish(ctx context.Context, topic, payload string) error
+	// Subscribe returns a channel receiving what is published to topic until ctx is done, then it is closed
+	Subscribe(ctx context.Context, topic string) (<-chan string, error)
+}
+
+// Memory delivers events within the process, so with several replicas each one only sees its own
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]map[chan string]struct{}
+}
+
+// Checking if Memory implements PubSub during compile time
+var _ PubSub = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]map[chan string]struct{})}
+}
+
+// Publish never blocks, a subscriber too slow to keep up misses events rather than holding up the publisher
+func (m *Memory) Publish(ctx context.Context, topic, payload string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.topics[topic] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan string, error) {
+	ch := make(chan string, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.topics[topic] == nil {
+		m.topics[topic] = make(map[chan string]struct{})
+	}
+	m.topics[topic][ch] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.topics[topic], ch)
+		if len(m.topics[topic]) == 0 {
+			delete(m.topics, topic)
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
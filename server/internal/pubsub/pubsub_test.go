@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan string) (string, bool) {
+	t.Helper()
+	select {
+	case payload, ok := <-ch:
+		return payload, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return "", false
+	}
+}
+
+func TestMemory(t *testing.T) {
+	t.Run("Delivers to every subscriber of the topic", func(t *testing.T) {
+		ps := NewMemory()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		first, err := ps.Subscribe(ctx, "movie:1")
+		require.NoError(t, err)
+		second, err := ps.Subscribe(ctx, "movie:1")
+		require.NoError(t, err)
+		other, err := ps.Subscribe(ctx, "movie:2")
+		require.NoError(t, err)
+
+		require.NoError(t, ps.Publish(ctx, "movie:1", "rated"))
+
+		for _, ch := range []<-chan string{first, second} {
+			payload, ok := receive(t, ch)
+			assert.True(t, ok)
+			assert.Equal(t, "rated", payload)
+		}
+		assert.Empty(t, other)
+	})
+
+	t.Run("Closes the channel when the subscriber is done", func(t *testing.T) {
+		ps := NewMemory()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		ch, err := ps.Subscribe(ctx, "movie:1")
+		require.NoError(t, err)
+		cancel()
+
+		_, ok := receive(t, ch)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool {
+			ps.mu.Lock()
+			defer ps.mu.Unlock()
+			return len(ps.topics) == 0
+		}, time.Second, time.Millisecond)
+		assert.NoError(t, ps.Publish(context.Background(), "movie:1", "rated"))
+	})
+
+	t.Run("Drops events a slow subscriber can't take", func(t *testing.T) {
+		ps := NewMemory()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch, err := ps.Subscribe(ctx, "movie:1")
+		require.NoError(t, err)
+		for i := 0; i < subscriberBuffer+5; i++ {
+			require.NoError(t, ps.Publish(ctx, "movie:1", "rated"))
+		}
+		assert.Len(t, ch, subscriberBuffer)
+	})
+}
@@ -0,0 +1,98 @@
+// Package querylimit rejects GraphQL operations that nest too deep or would cost too much to run
+package querylimit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	DefaultMaxDepth = 10
+	DefaultMaxCost  = 5000
+
+	errTooDeep   = "QUERY_TOO_DEEP"
+	errTooCostly = "QUERY_TOO_COSTLY"
+)
+
+// Limit is a gqlgen extension that checks every operation before it runs. The cost is the sum of the
+// complexity of each field, as configured in the executable schema's ComplexityRoot.
+type Limit struct {
+	MaxDepth int
+	MaxCost  int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &Limit{}
+
+func New(maxDepth, maxCost int) *Limit {
+	return &Limit{MaxDepth: maxDepth, MaxCost: maxCost}
+}
+
+func (l *Limit) ExtensionName() string {
+	return "QueryLimit"
+}
+
+func (l *Limit) Validate(schema graphql.ExecutableSchema) error {
+	if l.MaxDepth < 1 || l.MaxCost < 1 {
+		return errors.New("query depth and cost limits must be positive")
+	}
+	l.es = schema
+	return nil
+}
+
+func (l *Limit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op == nil {
+		// Left for the executor to report
+		return nil
+	}
+
+	// Checked first, it is cheap and bounds the work of computing the cost
+	if depth := Depth(op.SelectionSet); depth > l.MaxDepth {
+		err := gqlerror.Errorf("operation is nested %d levels deep, which exceeds the limit of %d", depth, l.MaxDepth)
+		err.Extensions = map[string]interface{}{"code": errTooDeep, "depth": depth, "maxDepth": l.MaxDepth}
+		return err
+	}
+
+	if cost := complexity.Calculate(l.es, op, rc.Variables); cost > l.MaxCost {
+		err := gqlerror.Errorf("operation has a cost of %d, which exceeds the limit of %d", cost, l.MaxCost)
+		err.Extensions = map[string]interface{}{"code": errTooCostly, "cost": cost, "maxCost": l.MaxCost}
+		return err
+	}
+	return nil
+}
+
+// Depth is how many levels of fields a selection set nests. Fragments don't add a level
+// and introspection is skipped, its own queries are deep but cheap.
+func Depth(selectionSet ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range selectionSet {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name == "__schema" || s.Name == "__type" {
+				continue
+			}
+			d = 1 + Depth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = Depth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = Depth(s.Definition.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
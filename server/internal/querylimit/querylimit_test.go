@@ -0,0 +1,116 @@
+package querylimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var testSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	type Query {
+		movies(pageSize: Int!): [Movie!]!
+		movie: Movie
+	}
+	type Movie {
+		title: String!
+		similar(pageSize: Int!): [Movie!]!
+	}
+`})
+
+// newTestServer serves testSchema, where lists cost pageSize times their items like connections do in the real schema
+func newTestServer(limit *Limit) *handler.Server {
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return testSchema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			if pageSize, ok := args["pageSize"].(int64); ok {
+				return int(pageSize) * childComplexity, true
+			}
+			return 0, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			return graphql.OneShot(&graphql.Response{Data: []byte(`{}`)})
+		},
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(limit)
+	return srv
+}
+
+func doQuery(t *testing.T, srv http.Handler, query string) map[string]interface{} {
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+
+	var resp struct {
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if len(resp.Errors) == 0 {
+		return nil
+	}
+	return resp.Errors[0].Extensions
+}
+
+func TestLimit(t *testing.T) {
+	srv := newTestServer(New(3, 100))
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "Within Limits",
+			query: `{ movies(pageSize: 10) { title similar(pageSize: 5) { title } } }`,
+			want:  nil,
+		},
+		{
+			name:  "Too Deep",
+			query: `{ movie { similar(pageSize: 1) { similar(pageSize: 1) { title } } } }`,
+			want:  map[string]interface{}{"code": errTooDeep, "depth": float64(4), "maxDepth": float64(3)},
+		},
+		{
+			name:  "Too Deep Through Fragments",
+			query: `{ movie { ...Similar } } fragment Similar on Movie { similar(pageSize: 1) { ... on Movie { similar(pageSize: 1) { title } } } }`,
+			want:  map[string]interface{}{"code": errTooDeep, "depth": float64(4), "maxDepth": float64(3)},
+		},
+		{
+			name:  "Too Costly",
+			query: `{ movies(pageSize: 20) { title similar(pageSize: 5) { title } } }`,
+			// 20 movies, each with a title and 5 similar movies with a title
+			want: map[string]interface{}{"code": errTooCostly, "cost": float64(20 * (1 + 5*1)), "maxCost": float64(100)},
+		},
+		{
+			name:  "Introspection Is Not Limited By Depth",
+			query: `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`,
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, doQuery(t, srv, tt.query))
+		})
+	}
+}
+
+func TestLimit_Validate(t *testing.T) {
+	assert.Error(t, New(0, 100).Validate(nil))
+	assert.NoError(t, New(DefaultMaxDepth, DefaultMaxCost).Validate(nil))
+}
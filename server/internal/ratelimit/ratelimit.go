@@ -0,0 +1,74 @@
+// Package ratelimit counts attempts per key, e.g. sign-ins per email address or per IP
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows a number of attempts per key within a fixed window. It is kept in memory,
+// so with several replicas each one enforces the limit on its own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  per,
+		now:     time.Now,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow records an attempt for key and reports whether it is within the limit.
+// When it isn't, it also returns how long until the key may try again.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// Reset forgets the attempts of key, e.g. after a successful sign-in
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, key)
+}
+
+// sweep drops finished windows once per window so keys that stop trying don't pile up
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	allowed, _ := l.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = l.Allow("a")
+	assert.True(t, allowed)
+
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter := l.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// Keys are counted separately
+	allowed, _ = l.Allow("b")
+	assert.True(t, allowed)
+
+	// A new window starts once the old one is over
+	now = now.Add(40 * time.Second)
+	allowed, _ = l.Allow("a")
+	assert.True(t, allowed)
+
+	l.Reset("a")
+	allowed, _ = l.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = l.Allow("a")
+	assert.True(t, allowed)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+
+	assert.Len(t, l.windows, 1)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type AccessTokenRepository struct {
+	db *sql.DB
+}
+
+// Checking if AccessTokenRepository implements AccessTokenRepositoryInterface during compile time
+var _ AccessTokenRepositoryInterface = (*AccessTokenRepository)(nil)
+
+func NewAccessTokenRepository(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+const accessTokenColumns = `id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+func scanAccessToken(row rowScanner) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, &token.TokenHash, pq.Array(&token.Scopes),
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *AccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	query := `INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(ctx, query,
+		token.ID, token.UserID, token.Name, token.TokenHash, pq.Array(token.Scopes), token.CreatedAt, token.ExpiresAt,
+	)
+	return err
+}
+
+func (r *AccessTokenRepository) GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.PersonalAccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + `
+              FROM personal_access_tokens
+              WHERE token_hash = $1`
+
+	token, err := scanAccessToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ListByUser returns the user's tokens that haven't been revoked, newest first
+func (r *AccessTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + `
+              FROM personal_access_tokens
+              WHERE user_id = $1 AND revoked_at IS NULL
+              ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *AccessTokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error {
+	query := `UPDATE personal_access_tokens
+              SET last_used_at = $1
+              WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, lastUsedAt, id)
+	return err
+}
+
+// Revoke marks one of the user's tokens as revoked. It reports false if there was no such active token.
+func (r *AccessTokenRepository) Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error) {
+	query := `UPDATE personal_access_tokens
+              SET revoked_at = $1
+              WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, revokedAt, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
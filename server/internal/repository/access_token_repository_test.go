@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var accessTokenRowColumns = []string{"id", "user_id", "name", "token_hash", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+func TestAccessTokenRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccessTokenRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				mock.ExpectExec("^INSERT INTO personal_access_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectExec("^INSERT INTO personal_access_tokens").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			token := &models.PersonalAccessToken{UserID: uuid.New(), Name: "ci", TokenHash: []byte("hash"), Scopes: []string{"read"}}
+			err := repo.Create(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccessTokenRepository.Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.NotEqual(t, uuid.Nil, token.ID)
+		})
+	}
+}
+
+func TestAccessTokenRepository_GetByTokenHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccessTokenRepository(db)
+	tokenID, userID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *models.PersonalAccessToken
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(accessTokenRowColumns).
+					AddRow(tokenID, userID, "ci", []byte("hash"), "{read,write}", now, nil, now, nil)
+				mock.ExpectQuery("^SELECT (.+) FROM personal_access_tokens WHERE token_hash").WillReturnRows(rows)
+			},
+			want: &models.PersonalAccessToken{
+				ID: tokenID, UserID: userID, Name: "ci", TokenHash: []byte("hash"), Scopes: []string{"read", "write"},
+				CreatedAt: now, LastUsedAt: &now,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM personal_access_tokens WHERE token_hash").WillReturnError(sql.ErrNoRows)
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM personal_access_tokens WHERE token_hash").WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByTokenHash(context.Background(), []byte("hash"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccessTokenRepository.GetByTokenHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAccessTokenRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccessTokenRepository(db)
+	userID := uuid.New()
+	now := time.Now()
+
+	rows := sqlmock.NewRows(accessTokenRowColumns).
+		AddRow(uuid.New(), userID, "ci", []byte("a"), "{read}", now, now.Add(time.Hour), nil, nil).
+		AddRow(uuid.New(), userID, "backup", []byte("b"), "{read,write}", now, nil, nil, nil)
+	mock.ExpectQuery("^SELECT (.+) FROM personal_access_tokens WHERE user_id = \\$1 AND revoked_at IS NULL").
+		WithArgs(userID).
+		WillReturnRows(rows)
+
+	tokens, err := repo.ListByUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, []string{"read", "write"}, tokens[1].Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccessTokenRepository_UpdateLastUsedAndRevoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccessTokenRepository(db)
+	tokenID, userID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectExec("^UPDATE personal_access_tokens SET last_used_at").WithArgs(now, tokenID).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdateLastUsed(context.Background(), tokenID, now))
+
+	mock.ExpectExec("^UPDATE personal_access_tokens SET revoked_at").WithArgs(now, tokenID, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	revoked, err := repo.Revoke(context.Background(), tokenID, userID, now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+)
+
+type AccountTokenRepository struct {
+	db *sql.DB
+}
+
+// Checking if AccountTokenRepository implements AccountTokenRepositoryInterface during compile time
+var _ AccountTokenRepositoryInterface = (*AccountTokenRepository)(nil)
+
+func NewAccountTokenRepository(db *sql.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+const accountTokenColumns = `id, user_id, purpose, token_hash, created_at, expires_at, used_at`
+
+func scanAccountToken(row rowScanner) (*models.AccountToken, error) {
+	var token models.AccountToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *AccountTokenRepository) Create(ctx context.Context, token *models.AccountToken) error {
+	query := `INSERT INTO account_tokens (id, user_id, purpose, token_hash, created_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+
+	token.ID = uuid.New()
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, query, token.ID, token.UserID, token.Purpose, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+// Redeem marks an unexpired token for purpose as used so its link only works once.
+// It returns nil if the token was already used, has expired, is for something else or never existed.
+func (r *AccountTokenRepository) Redeem(ctx context.Context, tokenHash []byte, purpose string, now time.Time) (*models.AccountToken, error) {
+	query := `UPDATE account_tokens
+              SET used_at = $3
+              WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+              RETURNING ` + accountTokenColumns
+
+	token, err := scanAccountToken(r.db.QueryRowContext(ctx, query, tokenHash, purpose, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ResetPassword redeems a password reset token and, in the same transaction, sets the new password of its user,
+// marks their email as verified and revokes all their sessions and personal access tokens.
+// It returns false and changes nothing if the token can't be redeemed, see Redeem.
+func (r *AccountTokenRepository) ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string, now time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRowContext(ctx, `UPDATE account_tokens
+              SET used_at = $3
+              WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+              RETURNING user_id`,
+		tokenHash, models.PurposeResetPassword, now,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem token: %w", err)
+	}
+
+	// Receiving the link proves owning the address, so it also verifies it
+	_, err = tx.ExecContext(ctx, `UPDATE users
+              SET password_hash = $1, email_verified_at = COALESCE(email_verified_at, $2)
+              WHERE id = $3`,
+		passwordHash, now, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update password: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`, now, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE personal_access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`, now, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var accountTokenRowColumns = []string{"id", "user_id", "purpose", "token_hash", "created_at", "expires_at", "used_at"}
+
+func TestAccountTokenRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccountTokenRepository(db)
+
+	mock.ExpectExec("^INSERT INTO account_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+	token := &models.AccountToken{UserID: uuid.New(), Purpose: models.PurposeVerifyEmail, TokenHash: []byte("hash"), ExpiresAt: time.Now().Add(time.Hour)}
+	assert.NoError(t, repo.Create(context.Background(), token))
+	assert.NotEqual(t, uuid.Nil, token.ID)
+	assert.False(t, token.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccountTokenRepository_Redeem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccountTokenRepository(db)
+	tokenHash := []byte("hash")
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(accountTokenRowColumns).
+					AddRow(uuid.New(), uuid.New(), models.PurposeResetPassword, tokenHash, now.Add(-time.Hour), now.Add(time.Hour), now)
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").WithArgs(tokenHash, models.PurposeResetPassword, now).WillReturnRows(rows)
+			},
+			wantFound: true,
+			wantErr:   false,
+		},
+		{
+			name: "Used Or Expired",
+			mockSetup: func() {
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").WithArgs(tokenHash, models.PurposeResetPassword, now).WillReturnError(sql.ErrNoRows)
+			},
+			wantFound: false,
+			wantErr:   false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").WithArgs(tokenHash, models.PurposeResetPassword, now).WillReturnError(sql.ErrConnDone)
+			},
+			wantFound: false,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.Redeem(context.Background(), tokenHash, models.PurposeResetPassword, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccountTokenRepository.Redeem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantFound, got != nil)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountTokenRepository_ResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAccountTokenRepository(db)
+	tokenHash := []byte("hash")
+	userID := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").
+					WithArgs(tokenHash, models.PurposeResetPassword, now).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+				mock.ExpectExec("^UPDATE users SET password_hash = \\$1, email_verified_at").WithArgs("new hash", now, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("^UPDATE sessions SET revoked_at").WithArgs(now, userID).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("^UPDATE personal_access_tokens SET revoked_at").WithArgs(now, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: true,
+		},
+		{
+			name: "Used Or Expired",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			want: false,
+		},
+		{
+			name: "Error - Nothing Kept When Revoking Fails",
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("^UPDATE account_tokens SET used_at").WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+				mock.ExpectExec("^UPDATE users SET password_hash").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("^UPDATE sessions SET revoked_at").WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.ResetPassword(context.Background(), tokenHash, "new hash", now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccountTokenRepository.ResetPassword() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
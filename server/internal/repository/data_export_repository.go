@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+)
+
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+// Checking if DataExportRepository implements DataExportRepositoryInterface during compile time
+var _ DataExportRepositoryInterface = (*DataExportRepository)(nil)
+
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+const dataExportColumns = `id, user_id, token_hash, created_at, expires_at, downloaded_at`
+
+func scanDataExport(row rowScanner) (*models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ID, &export.UserID, &export.TokenHash, &export.CreatedAt, &export.ExpiresAt, &export.DownloadedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *DataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	query := `INSERT INTO data_exports (id, user_id, token_hash, created_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5)`
+
+	export.ID = uuid.New()
+	if export.CreatedAt.IsZero() {
+		export.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, query, export.ID, export.UserID, export.TokenHash, export.CreatedAt, export.ExpiresAt)
+	return err
+}
+
+// Redeem marks an unexpired export as downloaded so its link only works once.
+// It returns nil if the link was already used, has expired or never existed.
+func (r *DataExportRepository) Redeem(ctx context.Context, tokenHash []byte, now time.Time) (*models.DataExport, error) {
+	query := `UPDATE data_exports
+              SET downloaded_at = $2
+              WHERE token_hash = $1 AND downloaded_at IS NULL AND expires_at > $2
+              RETURNING ` + dataExportColumns
+
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, query, tokenHash, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
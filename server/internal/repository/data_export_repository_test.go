@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var dataExportRowColumns = []string{"id", "user_id", "token_hash", "created_at", "expires_at", "downloaded_at"}
+
+func TestDataExportRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDataExportRepository(db)
+
+	mock.ExpectExec("^INSERT INTO data_exports").WillReturnResult(sqlmock.NewResult(1, 1))
+	export := &models.DataExport{UserID: uuid.New(), TokenHash: []byte("hash"), ExpiresAt: time.Now().Add(time.Hour)}
+	assert.NoError(t, repo.Create(context.Background(), export))
+	assert.NotEqual(t, uuid.Nil, export.ID)
+	assert.False(t, export.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDataExportRepository_Redeem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDataExportRepository(db)
+	tokenHash := []byte("hash")
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(dataExportRowColumns).
+					AddRow(uuid.New(), uuid.New(), tokenHash, now.Add(-time.Hour), now.Add(time.Hour), now)
+				mock.ExpectQuery("^UPDATE data_exports SET downloaded_at").WithArgs(tokenHash, now).WillReturnRows(rows)
+			},
+			wantFound: true,
+			wantErr:   false,
+		},
+		{
+			name: "Used Or Expired",
+			mockSetup: func() {
+				mock.ExpectQuery("^UPDATE data_exports SET downloaded_at").WithArgs(tokenHash, now).WillReturnError(sql.ErrNoRows)
+			},
+			wantFound: false,
+			wantErr:   false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^UPDATE data_exports SET downloaded_at").WithArgs(tokenHash, now).WillReturnError(sql.ErrConnDone)
+			},
+			wantFound: false,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.Redeem(context.Background(), tokenHash, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DataExportRepository.Redeem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantFound, got != nil)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Azanul/Next-Watch/internal/models"
 	"github.com/google/uuid"
@@ -11,7 +12,10 @@ import (
 type MovieRepositoryInterface interface {
 	GetMovies(ctx context.Context, searchTerm string, page, pageSize int) (*MoviePage, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Movie, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Movie, error)
 	GetByTitle(ctx context.Context, title string) (*models.Movie, error)
+	GetByTitlesAndYears(ctx context.Context, keys []TitleYear) ([]*models.Movie, error)
+	GetByYearRange(ctx context.Context, fromYear, toYear int) ([]*models.Movie, error)
 	GetSimilarMovies(ctx context.Context, embedding pgvector.Vector, page, pageSize int) (*MoviePage, error)
 	Create(ctx context.Context, movie *models.Movie) error
 	Update(ctx context.Context, movie *models.Movie) error
@@ -20,14 +24,69 @@ type MovieRepositoryInterface interface {
 
 type RatingRepositoryInterface interface {
 	GetByID(ctx context.Context, ratingID uuid.UUID) (*models.Rating, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Rating, error)
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Rating, error)
 	GetByUserAndMovie(ctx context.Context, userID, movieID uuid.UUID) (*models.Rating, error)
+	GetByUserAndMovies(ctx context.Context, userID uuid.UUID, movieIDs []uuid.UUID) (map[uuid.UUID]*models.Rating, error)
 	Create(ctx context.Context, rating *models.Rating) error
 	Update(ctx context.Context, rating *models.Rating) error
-	Delete(ctx context.Context, ratingID uuid.UUID) (*models.Rating, error)
+	SaveBatch(ctx context.Context, user *models.User, ratings []*models.Rating) error
+	Delete(ctx context.Context, ratingID uuid.UUID, owner *models.User) (bool, error)
+	ForEachByUser(ctx context.Context, userID uuid.UUID, fn func(*RatedMovie) error) error
+	GetSummaryByUser(ctx context.Context, userID uuid.UUID) (*RatingSummary, error)
+	GetSummariesByUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*RatingSummary, error)
+	GetSummaryByMovie(ctx context.Context, movieID uuid.UUID) (*RatingSummary, error)
+	GetTopGenresByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*GenreSummary, error)
 }
 
 type UserRepositoryInterface interface {
 	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	Search(ctx context.Context, searchTerm string, page, pageSize int) (*UserPage, error)
+	UpdateRole(ctx context.Context, id uuid.UUID, role string) error
+	UpdateSuspension(ctx context.Context, id uuid.UUID, suspendedAt *time.Time) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type SessionRepositoryInterface interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.Session, error)
+	ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.Session, error)
+	Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error
+	Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error)
+	RevokeAllByUser(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error
+}
+
+type AccessTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.PersonalAccessToken, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error)
+	UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error
+	Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error)
+}
+
+type DataExportRepositoryInterface interface {
+	Create(ctx context.Context, export *models.DataExport) error
+	Redeem(ctx context.Context, tokenHash []byte, now time.Time) (*models.DataExport, error)
+}
+
+type AccountTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *models.AccountToken) error
+	Redeem(ctx context.Context, tokenHash []byte, purpose string, now time.Time) (*models.AccountToken, error)
+	ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string, now time.Time) (bool, error)
+}
+
+type UserIdentityRepositoryInterface interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.UserIdentity, error)
+	UpdateEmail(ctx context.Context, id uuid.UUID, email string) error
+	UpdateRefreshToken(ctx context.Context, id uuid.UUID, refreshToken string) error
+	Delete(ctx context.Context, id, userID uuid.UUID) (bool, error)
 }
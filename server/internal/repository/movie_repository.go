@@ -9,6 +9,7 @@ import (
 
 	"github.com/Azanul/Next-Watch/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 )
 
@@ -125,6 +126,35 @@ func (r *MovieRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Mo
 	return &movie, nil
 }
 
+// GetByIDs returns the movies with the given IDs in no particular order, skipping IDs that don't exist.
+// Embeddings are left out, only recommendations need them.
+func (r *MovieRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Movie, error) {
+	query := `SELECT id, title, genre, year, wiki, plot, director, "cast"
+              FROM movies
+              WHERE id = ANY($1::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	var movies []*models.Movie
+	for rows.Next() {
+		var movie models.Movie
+		err := rows.Scan(&movie.ID, &movie.Title, &movie.Genre, &movie.Year, &movie.Wiki, &movie.Plot, &movie.Director, &movie.Cast)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
 func (r *MovieRepository) GetByTitle(ctx context.Context, title string) (*models.Movie, error) {
 	query := `SELECT id, genre, year, wiki, plot, director, "cast" 
               FROM movies 
@@ -144,6 +174,59 @@ func (r *MovieRepository) GetByTitle(ctx context.Context, title string) (*models
 	return &movie, nil
 }
 
+// TitleYear identifies a movie the way exported ratings files do
+type TitleYear struct {
+	Title string
+	Year  int
+}
+
+// GetByTitlesAndYears looks all keys up at once, matching titles case-insensitively.
+// Keys without a movie are skipped, a key that matches several movies gets one of them.
+func (r *MovieRepository) GetByTitlesAndYears(ctx context.Context, keys []TitleYear) ([]*models.Movie, error) {
+	query := `SELECT DISTINCT ON (LOWER(m.title), m.year) m.id, m.title, m.genre, m.year, m.wiki, m.plot, m.director, m."cast", m.embedding
+              FROM movies m
+              JOIN unnest($1::text[], $2::int[]) AS k(title, year) ON LOWER(m.title) = LOWER(k.title) AND m.year = k.year`
+
+	titles := make([]string, len(keys))
+	years := make([]int64, len(keys))
+	for i, key := range keys {
+		titles[i], years[i] = key.Title, int64(key.Year)
+	}
+	return r.queryMovies(ctx, query, pq.Array(titles), pq.Array(years))
+}
+
+func (r *MovieRepository) GetByYearRange(ctx context.Context, fromYear, toYear int) ([]*models.Movie, error) {
+	query := `SELECT id, title, genre, year, wiki, plot, director, "cast", embedding
+              FROM movies
+              WHERE year BETWEEN $1 AND $2`
+
+	return r.queryMovies(ctx, query, fromYear, toYear)
+}
+
+// queryMovies runs a query selecting the movie columns including the embedding
+func (r *MovieRepository) queryMovies(ctx context.Context, query string, args ...interface{}) ([]*models.Movie, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	var movies []*models.Movie
+	for rows.Next() {
+		var movie models.Movie
+		err := rows.Scan(&movie.ID, &movie.Title, &movie.Genre, &movie.Year, &movie.Wiki, &movie.Plot, &movie.Director, &movie.Cast, &movie.Embedding)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
 func (r *MovieRepository) GetSimilarMovies(ctx context.Context, embedding pgvector.Vector, page, pageSize int) (*MoviePage, error) {
 	offset := (page - 1) * pageSize
 
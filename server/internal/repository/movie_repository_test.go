@@ -8,6 +8,7 @@ import (
 	"github.com/Azanul/Next-Watch/internal/models"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 	"github.com/stretchr/testify/assert"
 )
@@ -163,6 +164,58 @@ func TestMovieRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestMovieRepository_GetByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewMovieRepository(db)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "title", "genre", "year", "wiki", "plot", "director", "cast"}).
+					AddRow(ids[0], "Movie 1", "Action", 2020, "wiki1", "plot1", "director1", "cast1")
+				mock.ExpectQuery("^SELECT (.+) FROM movies WHERE id = ANY").
+					WithArgs(uuidArray(ids)).
+					WillReturnRows(rows)
+			},
+			wantLen: 1,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM movies WHERE id = ANY").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByIDs(context.Background(), ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MovieRepository.GetByIDs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestMovieRepository_GetByTitle(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -226,6 +279,111 @@ func TestMovieRepository_GetByTitle(t *testing.T) {
 	}
 }
 
+func TestMovieRepository_GetByTitlesAndYears(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewMovieRepository(db)
+	keys := []TitleYear{{Title: "test movie", Year: 2021}, {Title: "Non-existent Movie", Year: 1999}}
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "title", "genre", "year", "wiki", "plot", "director", "cast", "embedding"}).
+					AddRow(uuid.New(), "Test Movie", "Action", 2021, "wiki", "plot", "director", "cast", pgvector.NewVector([]float32{1, 2, 3}))
+				mock.ExpectQuery("^SELECT DISTINCT ON (.+) FROM movies m JOIN unnest").
+					WithArgs(pq.Array([]string{"test movie", "Non-existent Movie"}), pq.Array([]int64{2021, 1999})).
+					WillReturnRows(rows)
+			},
+			wantLen: 1,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT DISTINCT ON (.+) FROM movies m JOIN unnest").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByTitlesAndYears(context.Background(), keys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MovieRepository.GetByTitlesAndYears() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+			if tt.wantLen > 0 {
+				assert.Equal(t, "Test Movie", got[0].Title)
+				assert.Len(t, got[0].Embedding.Slice(), 3)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMovieRepository_GetByYearRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewMovieRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "title", "genre", "year", "wiki", "plot", "director", "cast", "embedding"}).
+					AddRow(uuid.New(), "Movie 1", "Action", 2020, "wiki1", "plot1", "director1", "cast1", pgvector.NewVector([]float32{1, 2, 3})).
+					AddRow(uuid.New(), "Movie 2", "Drama", 2021, "wiki2", "plot2", "director2", "cast2", pgvector.NewVector([]float32{4, 5, 6}))
+				mock.ExpectQuery("^SELECT (.+) FROM movies WHERE year BETWEEN").WithArgs(2020, 2022).WillReturnRows(rows)
+			},
+			wantLen: 2,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM movies WHERE year BETWEEN").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByYearRange(context.Background(), 2020, 2022)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MovieRepository.GetByYearRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+		})
+	}
+}
+
 func TestMovieRepository_GetSimilarMovies(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/Azanul/Next-Watch/internal/models"
@@ -39,6 +40,63 @@ func (r *RatingRepository) GetByID(ctx context.Context, ratingID uuid.UUID) (*mo
 	return &rating, nil
 }
 
+// GetByIDs returns the ratings that exist among ids, in no particular order
+func (r *RatingRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Rating, error) {
+	query := `SELECT id, user_id, movie_id, score, created_at, updated_at 
+              FROM ratings 
+              WHERE id = ANY($1::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var ratings []*models.Rating
+	for rows.Next() {
+		var rating models.Rating
+		err := rows.Scan(&rating.ID, &rating.UserID, &rating.MovieID, &rating.Score, &rating.CreatedAt, &rating.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// GetByUser returns the user's ratings, most recently updated first
+func (r *RatingRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Rating, error) {
+	query := `SELECT id, user_id, movie_id, score, created_at, updated_at 
+              FROM ratings 
+              WHERE user_id = $1
+              ORDER BY updated_at DESC, id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var ratings []*models.Rating
+	for rows.Next() {
+		var rating models.Rating
+		err := rows.Scan(&rating.ID, &rating.UserID, &rating.MovieID, &rating.Score, &rating.CreatedAt, &rating.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
 func (r *RatingRepository) GetByUserAndMovie(ctx context.Context, userID, movieID uuid.UUID) (*models.Rating, error) {
 	query := `SELECT id, user_id, movie_id, score, created_at, updated_at 
               FROM ratings 
@@ -57,6 +115,34 @@ func (r *RatingRepository) GetByUserAndMovie(ctx context.Context, userID, movieI
 	return &rating, nil
 }
 
+// GetByUserAndMovies is GetByUserAndMovie for several movies at once, keyed by movie ID. Movies the user hasn't rated are left out.
+func (r *RatingRepository) GetByUserAndMovies(ctx context.Context, userID uuid.UUID, movieIDs []uuid.UUID) (map[uuid.UUID]*models.Rating, error) {
+	query := `SELECT id, user_id, movie_id, score, created_at, updated_at 
+              FROM ratings 
+              WHERE user_id = $1 AND movie_id = ANY($2::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, uuidArray(movieIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := make(map[uuid.UUID]*models.Rating)
+	for rows.Next() {
+		var rating models.Rating
+		err := rows.Scan(&rating.ID, &rating.UserID, &rating.MovieID, &rating.Score, &rating.CreatedAt, &rating.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ratings[rating.MovieID] = &rating
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
 func (r *RatingRepository) Create(ctx context.Context, rating *models.Rating) error {
 	query := `INSERT INTO ratings (id, user_id, movie_id, score, created_at, updated_at) 
               VALUES ($1, $2, $3, $4, $5, $6)`
@@ -82,26 +168,223 @@ func (r *RatingRepository) Update(ctx context.Context, rating *models.Rating) er
 	return err
 }
 
-func (r *RatingRepository) Delete(ctx context.Context, ratingID uuid.UUID) (*models.Rating, error) {
-	query := `DELETE FROM ratings 
-              WHERE id = $1
-              RETURNING id, user_id, movie_id, score, created_at, updated_at`
+// SaveBatch stores ratings of a user together with the user's taste and score statistics in one transaction,
+// so either all of them are saved or none. Ratings without an ID are created, the others updated.
+func (r *RatingRepository) SaveBatch(ctx context.Context, user *models.User, ratings []*models.Rating) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	var deletedRating models.Rating
-	err := r.db.QueryRowContext(ctx, query, ratingID).Scan(
-		&deletedRating.ID,
-		&deletedRating.UserID,
-		&deletedRating.MovieID,
-		&deletedRating.Score,
-		&deletedRating.CreatedAt,
-		&deletedRating.UpdatedAt,
+	now := time.Now()
+	for _, rating := range ratings {
+		if rating.ID == uuid.Nil {
+			rating.ID = uuid.New()
+			rating.CreatedAt = now
+			rating.UpdatedAt = now
+			_, err = tx.ExecContext(ctx, `INSERT INTO ratings (id, user_id, movie_id, score, created_at, updated_at) 
+              VALUES ($1, $2, $3, $4, $5, $6)`,
+				rating.ID, rating.UserID, rating.MovieID, rating.Score, rating.CreatedAt, rating.UpdatedAt,
+			)
+		} else {
+			rating.UpdatedAt = now
+			_, err = tx.ExecContext(ctx, `UPDATE ratings 
+              SET score = $1, updated_at = $2 
+              WHERE id = $3`,
+				rating.Score, rating.UpdatedAt, rating.ID,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save rating: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users 
+              SET taste = $1, rating_count = $2, score_mean = $3, score_m2 = $4
+              WHERE id = $5`,
+		user.Taste, user.ScoreStats.Count, user.ScoreStats.Mean, user.ScoreStats.M2, user.ID,
 	)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to update taste: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a rating and saves the score statistics of its owner, which no longer count the rating,
+// in one transaction. It returns false and changes nothing when the rating doesn't exist.
+func (r *RatingRepository) Delete(ctx context.Context, ratingID uuid.UUID, owner *models.User) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM ratings 
+              WHERE id = $1`, ratingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rating: %w", err)
 	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users 
+              SET rating_count = $1, score_mean = $2, score_m2 = $3
+              WHERE id = $4`,
+		owner.ScoreStats.Count, owner.ScoreStats.Mean, owner.ScoreStats.M2, owner.ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update score statistics: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RatedMovie is a rating together with the movie it was given to
+type RatedMovie struct {
+	Rating *models.Rating
+	Movie  *models.Movie
+}
+
+// ForEachByUser calls fn for every rating of a user, oldest first, without loading them all into memory
+func (r *RatingRepository) ForEachByUser(ctx context.Context, userID uuid.UUID, fn func(*RatedMovie) error) error {
+	query := `SELECT r.id, r.movie_id, r.score, r.created_at, r.updated_at, m.title, m.year
+              FROM ratings r
+              JOIN movies m ON m.id = r.movie_id
+              WHERE r.user_id = $1
+              ORDER BY r.created_at, r.id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rating := models.Rating{UserID: userID}
+		var movie models.Movie
+		err := rows.Scan(&rating.ID, &rating.MovieID, &rating.Score, &rating.CreatedAt, &rating.UpdatedAt, &movie.Title, &movie.Year)
+		if err != nil {
+			return err
+		}
+		movie.ID = rating.MovieID
+
+		if err := fn(&RatedMovie{Rating: &rating, Movie: &movie}); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// RatingSummary aggregates all ratings of a user, or all ratings of a movie
+type RatingSummary struct {
+	Count        int
+	AverageScore float64
+}
+
+// GenreSummary aggregates a user's ratings of a single genre
+type GenreSummary struct {
+	Genre        string
+	Count        int
+	AverageScore float64
+}
+
+func (r *RatingRepository) GetSummaryByUser(ctx context.Context, userID uuid.UUID) (*RatingSummary, error) {
+	query := `SELECT COUNT(*), COALESCE(AVG(score), 0)
+              FROM ratings
+              WHERE user_id = $1`
+
+	var summary RatingSummary
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&summary.Count, &summary.AverageScore)
 	if err != nil {
 		return nil, err
 	}
+	return &summary, nil
+}
+
+// GetSummariesByUsers is GetSummaryByUser for several users at once. Every user gets a summary, users without ratings an empty one.
+func (r *RatingRepository) GetSummariesByUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*RatingSummary, error) {
+	query := `SELECT user_id, COUNT(*), AVG(score)
+              FROM ratings
+              WHERE user_id = ANY($1::uuid[])
+              GROUP BY user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rating summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[uuid.UUID]*RatingSummary, len(userIDs))
+	for _, userID := range userIDs {
+		summaries[userID] = &RatingSummary{}
+	}
+	for rows.Next() {
+		var userID uuid.UUID
+		var summary RatingSummary
+		if err := rows.Scan(&userID, &summary.Count, &summary.AverageScore); err != nil {
+			return nil, err
+		}
+		summaries[userID] = &summary
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// GetSummaryByMovie aggregates the ratings every user gave a movie
+func (r *RatingRepository) GetSummaryByMovie(ctx context.Context, movieID uuid.UUID) (*RatingSummary, error) {
+	query := `SELECT COUNT(*), COALESCE(AVG(score), 0)
+              FROM ratings
+              WHERE movie_id = $1`
 
-	return &deletedRating, nil
+	var summary RatingSummary
+	err := r.db.QueryRowContext(ctx, query, movieID).Scan(&summary.Count, &summary.AverageScore)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// GetTopGenresByUser returns the genres a user rated highest overall, favouring genres they rated often
+func (r *RatingRepository) GetTopGenresByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*GenreSummary, error) {
+	query := `SELECT m.genre, COUNT(*), AVG(r.score)
+              FROM ratings r
+              JOIN movies m ON m.id = r.movie_id
+              WHERE r.user_id = $1 AND m.genre <> '' AND m.genre <> 'unknown'
+              GROUP BY m.genre
+              ORDER BY SUM(r.score) DESC, m.genre
+              LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query genres: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []*GenreSummary
+	for rows.Next() {
+		var genre GenreSummary
+		if err := rows.Scan(&genre.Genre, &genre.Count, &genre.AverageScore); err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return genres, nil
 }
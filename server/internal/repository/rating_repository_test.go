@@ -75,6 +75,121 @@ func TestRatingRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestRatingRepository_GetByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "movie_id", "score", "created_at", "updated_at"}).
+					AddRow(ids[0], uuid.New(), uuid.New(), 5, time.Now(), time.Now())
+				mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE id = ANY").
+					WithArgs(uuidArray(ids)).
+					WillReturnRows(rows)
+			},
+			wantLen: 1,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE id = ANY").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByIDs(context.Background(), ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.GetByIDs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRatingRepository_GetByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "movie_id", "score", "created_at", "updated_at"}).
+					AddRow(uuid.New(), userID, uuid.New(), 5, time.Now(), time.Now()).
+					AddRow(uuid.New(), userID, uuid.New(), 3, time.Now(), time.Now())
+				mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE user_id = (.+) ORDER BY updated_at DESC").
+					WithArgs(userID).
+					WillReturnRows(rows)
+			},
+			wantLen: 2,
+			wantErr: false,
+		},
+		{
+			name: "No Ratings",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE user_id").
+					WithArgs(userID).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "movie_id", "score", "created_at", "updated_at"}))
+			},
+			wantLen: 0,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE user_id").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByUser(context.Background(), userID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.GetByUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestRatingRepository_GetByUserAndMovie(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -142,6 +257,30 @@ func TestRatingRepository_GetByUserAndMovie(t *testing.T) {
 	}
 }
 
+func TestRatingRepository_GetByUserAndMovies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	userID := uuid.New()
+	ratedMovieID, unratedMovieID := uuid.New(), uuid.New()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "movie_id", "score", "created_at", "updated_at"}).
+		AddRow(uuid.New(), userID, ratedMovieID, 4, time.Now(), time.Now())
+	mock.ExpectQuery("^SELECT (.+) FROM ratings WHERE").
+		WithArgs(userID, uuidArray([]uuid.UUID{ratedMovieID, unratedMovieID})).
+		WillReturnRows(rows)
+
+	got, err := repo.GetByUserAndMovies(context.Background(), userID, []uuid.UUID{ratedMovieID, unratedMovieID})
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, float32(4), got[ratedMovieID].Score)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRatingRepository_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -254,39 +393,277 @@ func TestRatingRepository_Delete(t *testing.T) {
 	defer db.Close()
 
 	repo := NewRatingRepository(db)
+	owner := &models.User{ID: uuid.New(), ScoreStats: models.ScoreStats{Count: 2, Mean: 3.5, M2: 0.5}}
 
 	tests := []struct {
 		name      string
 		ratingID  uuid.UUID
-		mockSetup func()
-		want      *models.Rating
+		mockSetup func(ratingID uuid.UUID)
+		want      bool
 		wantErr   bool
 	}{
 		{
 			name:     "Success",
 			ratingID: uuid.New(),
-			mockSetup: func() {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "movie_id", "score", "created_at", "updated_at"}).
-					AddRow(uuid.New(), uuid.New(), uuid.New(), 5, time.Now(), time.Now())
-				mock.ExpectQuery("^DELETE FROM ratings WHERE").WillReturnRows(rows)
+			mockSetup: func(ratingID uuid.UUID) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^DELETE FROM ratings WHERE").WithArgs(ratingID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("^UPDATE users SET rating_count").WithArgs(2, 3.5, 0.5, owner.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
-			want:    &models.Rating{},
+			want:    true,
 			wantErr: false,
 		},
 		{
 			name:     "Not Found",
 			ratingID: uuid.New(),
+			mockSetup: func(ratingID uuid.UUID) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^DELETE FROM ratings WHERE").WithArgs(ratingID).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:     "Rolls Back On Error",
+			ratingID: uuid.New(),
+			mockSetup: func(ratingID uuid.UUID) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^DELETE FROM ratings WHERE").WithArgs(ratingID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("^UPDATE users SET rating_count").WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup(tt.ratingID)
+
+			got, err := repo.Delete(context.Background(), tt.ratingID, owner)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.Delete() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRatingRepository_SaveBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	user := &models.User{ID: uuid.New()}
+
+	tests := []struct {
+		name      string
+		ratings   []*models.Rating
+		mockSetup func()
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			ratings: []*models.Rating{
+				{UserID: user.ID, MovieID: uuid.New(), Score: 5},
+				{ID: uuid.New(), UserID: user.ID, MovieID: uuid.New(), Score: 2},
+			},
 			mockSetup: func() {
-				mock.ExpectQuery("^DELETE FROM ratings WHERE").WillReturnError(sql.ErrNoRows)
+				mock.ExpectBegin()
+				mock.ExpectExec("^INSERT INTO ratings").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("^UPDATE ratings").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("^UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Rolls Back On Error",
+			ratings: []*models.Rating{
+				{UserID: user.ID, MovieID: uuid.New(), Score: 5},
+				{UserID: user.ID, MovieID: uuid.New(), Score: 3},
+			},
+			mockSetup: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("^INSERT INTO ratings").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("^INSERT INTO ratings").WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			err := repo.SaveBatch(context.Background(), user, tt.ratings)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.SaveBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				for _, rating := range tt.ratings {
+					assert.NotEqual(t, uuid.Nil, rating.ID)
+				}
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRatingRepository_ForEachByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		fnErr     error
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "movie_id", "score", "created_at", "updated_at", "title", "year"}).
+					AddRow(uuid.New(), uuid.New(), 4.5, time.Now(), time.Now(), "Movie 1", 2001).
+					AddRow(uuid.New(), uuid.New(), 2, time.Now(), time.Now(), "Movie 2", 2002)
+				mock.ExpectQuery("^SELECT (.+) FROM ratings r JOIN movies m").WithArgs(userID).WillReturnRows(rows)
+			},
+			wantCount: 2,
+			wantErr:   false,
+		},
+		{
+			name: "Callback Error Stops Iteration",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"id", "movie_id", "score", "created_at", "updated_at", "title", "year"}).
+					AddRow(uuid.New(), uuid.New(), 4.5, time.Now(), time.Now(), "Movie 1", 2001).
+					AddRow(uuid.New(), uuid.New(), 2, time.Now(), time.Now(), "Movie 2", 2002)
+				mock.ExpectQuery("^SELECT (.+) FROM ratings r JOIN movies m").WithArgs(userID).WillReturnRows(rows)
+			},
+			fnErr:     sql.ErrTxDone,
+			wantCount: 1,
+			wantErr:   true,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM ratings r JOIN movies m").WillReturnError(sql.ErrConnDone)
+			},
+			wantCount: 0,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			count := 0
+			err := repo.ForEachByUser(context.Background(), userID, func(rm *RatedMovie) error {
+				count++
+				assert.Equal(t, userID, rm.Rating.UserID)
+				assert.Equal(t, rm.Rating.MovieID, rm.Movie.ID)
+				return tt.fnErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.ForEachByUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantCount, count)
+		})
+	}
+}
+
+func TestRatingRepository_GetSummaryByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *RatingSummary
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"count", "avg"}).AddRow(3, 3.5)
+				mock.ExpectQuery("^SELECT COUNT(.+) FROM ratings WHERE").WillReturnRows(rows)
+			},
+			want:    &RatingSummary{Count: 3, AverageScore: 3.5},
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT COUNT(.+) FROM ratings WHERE").WillReturnError(sql.ErrConnDone)
 			},
 			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetSummaryByUser(context.Background(), uuid.New())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.GetSummaryByUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRatingRepository_GetSummaryByMovie(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *RatingSummary
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"count", "avg"}).AddRow(12, 4.25)
+				mock.ExpectQuery("^SELECT COUNT(.+) FROM ratings WHERE").WillReturnRows(rows)
+			},
+			want:    &RatingSummary{Count: 12, AverageScore: 4.25},
 			wantErr: false,
 		},
 		{
-			name:     "Error",
-			ratingID: uuid.New(),
+			name: "Error",
 			mockSetup: func() {
-				mock.ExpectQuery("^DELETE FROM ratings WHERE").WillReturnError(sql.ErrConnDone)
+				mock.ExpectQuery("^SELECT COUNT(.+) FROM ratings WHERE").WillReturnError(sql.ErrConnDone)
 			},
 			want:    nil,
 			wantErr: true,
@@ -297,14 +674,95 @@ func TestRatingRepository_Delete(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 
-			got, err := repo.Delete(context.Background(), tt.ratingID)
+			got, err := repo.GetSummaryByMovie(context.Background(), uuid.New())
 			if (err != nil) != tt.wantErr {
-				t.Errorf("RatingRepository.Delete() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("RatingRepository.GetSummaryByMovie() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && got != nil {
-				assert.IsType(t, &models.Rating{}, got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRatingRepository_GetSummariesByUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+	rater, newcomer := uuid.New(), uuid.New()
+	userIDs := []uuid.UUID{rater, newcomer}
+
+	rows := sqlmock.NewRows([]string{"user_id", "count", "avg"}).AddRow(rater, 3, 3.5)
+	mock.ExpectQuery("^SELECT user_id, COUNT(.+) FROM ratings WHERE user_id = ANY").
+		WithArgs(uuidArray(userIDs)).
+		WillReturnRows(rows)
+
+	summaries, err := repo.GetSummariesByUsers(context.Background(), userIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]*RatingSummary{
+		rater:    {Count: 3, AverageScore: 3.5},
+		newcomer: {},
+	}, summaries)
+
+	mock.ExpectQuery("^SELECT user_id, COUNT(.+) FROM ratings WHERE user_id = ANY").WillReturnError(sql.ErrConnDone)
+	_, err = repo.GetSummariesByUsers(context.Background(), userIDs)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRatingRepository_GetTopGenresByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRatingRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      []*GenreSummary
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows([]string{"genre", "count", "avg"}).
+					AddRow("drama", 4, 4.25).
+					AddRow("comedy", 2, 3.0)
+				mock.ExpectQuery("^SELECT (.+) FROM ratings r JOIN movies m (.+) GROUP BY m.genre").WithArgs(sqlmock.AnyArg(), 3).WillReturnRows(rows)
+			},
+			want: []*GenreSummary{
+				{Genre: "drama", Count: 4, AverageScore: 4.25},
+				{Genre: "comedy", Count: 2, AverageScore: 3.0},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM ratings r JOIN movies m").WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetTopGenresByUser(context.Background(), uuid.New(), 3)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RatingRepository.GetTopGenresByUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
 			}
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// Checking if SessionRepository implements SessionRepositoryInterface during compile time
+var _ SessionRepositoryInterface = (*SessionRepository)(nil)
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.TokenHash, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO sessions (id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	session.ID = uuid.New()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	session.LastSeenAt = session.CreatedAt
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID, session.UserID, session.TokenHash, session.UserAgent, session.IPAddress,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt,
+	)
+	return err
+}
+
+func (r *SessionRepository) GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + `
+              FROM sessions
+              WHERE token_hash = $1`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListActiveByUser returns the user's sessions that are neither revoked nor past their absolute expiry,
+// most recently used first
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + `
+              FROM sessions
+              WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+              ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListByUser returns all of the user's sessions including revoked and expired ones, newest first
+func (r *SessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + `
+              FROM sessions
+              WHERE user_id = $1
+              ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	query := `UPDATE sessions
+              SET last_seen_at = $1
+              WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, lastSeenAt, id)
+	return err
+}
+
+// Revoke marks one of the user's sessions as revoked. It reports false if there was no such active session.
+func (r *SessionRepository) Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error) {
+	query := `UPDATE sessions
+              SET revoked_at = $1
+              WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, revokedAt, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// RevokeAllByUser signs the user out everywhere, e.g. after their password was reset
+func (r *SessionRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	query := `UPDATE sessions
+              SET revoked_at = $1
+              WHERE user_id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, revokedAt, userID)
+	return err
+}
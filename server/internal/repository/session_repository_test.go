@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionRowColumns = []string{"id", "user_id", "token_hash", "user_agent", "ip_address", "created_at", "last_seen_at", "expires_at", "revoked_at"}
+
+func TestSessionRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSessionRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				mock.ExpectExec("^INSERT INTO sessions").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectExec("^INSERT INTO sessions").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			session := &models.Session{UserID: uuid.New(), TokenHash: []byte("hash"), ExpiresAt: time.Now().Add(time.Hour)}
+			err := repo.Create(context.Background(), session)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionRepository.Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.NotEqual(t, uuid.Nil, session.ID)
+			assert.Equal(t, session.CreatedAt, session.LastSeenAt)
+		})
+	}
+}
+
+func TestSessionRepository_GetByTokenHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSessionRepository(db)
+	sessionID := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *models.Session
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(sessionRowColumns).
+					AddRow(sessionID, sessionID, []byte("hash"), "Firefox", "127.0.0.1", now, now, now.Add(time.Hour), nil)
+				mock.ExpectQuery("^SELECT (.+) FROM sessions WHERE token_hash").WillReturnRows(rows)
+			},
+			want: &models.Session{
+				ID: sessionID, UserID: sessionID, TokenHash: []byte("hash"), UserAgent: "Firefox", IPAddress: "127.0.0.1",
+				CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour),
+			},
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM sessions WHERE token_hash").WillReturnError(sql.ErrNoRows)
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM sessions WHERE token_hash").WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByTokenHash(context.Background(), []byte("hash"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SessionRepository.GetByTokenHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSessionRepository_ListActiveByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSessionRepository(db)
+	userID := uuid.New()
+	now := time.Now()
+
+	rows := sqlmock.NewRows(sessionRowColumns).
+		AddRow(uuid.New(), userID, []byte("a"), "Firefox", "127.0.0.1", now, now, now.Add(time.Hour), nil).
+		AddRow(uuid.New(), userID, []byte("b"), "Safari", "10.0.0.1", now, now.Add(-time.Hour), now.Add(time.Hour), nil)
+	mock.ExpectQuery("^SELECT (.+) FROM sessions WHERE user_id = \\$1 AND revoked_at IS NULL").
+		WithArgs(userID, now).
+		WillReturnRows(rows)
+
+	sessions, err := repo.ListActiveByUser(context.Background(), userID, now)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "Safari", sessions[1].UserAgent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSessionRepository(db)
+	userID := uuid.New()
+	now := time.Now()
+
+	rows := sqlmock.NewRows(sessionRowColumns).
+		AddRow(uuid.New(), userID, []byte("a"), "Firefox", "127.0.0.1", now, now, now.Add(time.Hour), nil).
+		AddRow(uuid.New(), userID, []byte("b"), "Safari", "10.0.0.1", now, now, now.Add(-time.Hour), now)
+	mock.ExpectQuery("^SELECT (.+) FROM sessions WHERE user_id = \\$1 ORDER BY created_at").
+		WithArgs(userID).
+		WillReturnRows(rows)
+
+	sessions, err := repo.ListByUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.NotNil(t, sessions[1].RevokedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_TouchAndRevoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewSessionRepository(db)
+	sessionID, userID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectExec("^UPDATE sessions SET last_seen_at").WithArgs(now, sessionID).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.Touch(context.Background(), sessionID, now))
+
+	mock.ExpectExec("^UPDATE sessions SET revoked_at").WithArgs(now, sessionID, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	revoked, err := repo.Revoke(context.Background(), sessionID, userID, now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	mock.ExpectExec("^UPDATE sessions SET revoked_at").WithArgs(now, sessionID, userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	revoked, err = repo.Revoke(context.Background(), sessionID, userID, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	mock.ExpectExec("^UPDATE sessions SET revoked_at (.+) WHERE user_id").WithArgs(now, userID).WillReturnResult(sqlmock.NewResult(0, 2))
+	assert.NoError(t, repo.RevokeAllByUser(context.Background(), userID, now))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+)
+
+type UserIdentityRepository struct {
+	db *sql.DB
+}
+
+// Checking if UserIdentityRepository implements UserIdentityRepositoryInterface during compile time
+var _ UserIdentityRepositoryInterface = (*UserIdentityRepository)(nil)
+
+func NewUserIdentityRepository(db *sql.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+const userIdentityColumns = `id, user_id, provider, subject, email, created_at, refresh_token`
+
+func scanUserIdentity(row rowScanner) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *UserIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	query := `INSERT INTO user_identities (id, user_id, provider, subject, email, created_at, refresh_token)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	identity.ID = uuid.New()
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, query, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.RefreshToken)
+	return err
+}
+
+func (r *UserIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	identity, err := scanUserIdentity(r.db.QueryRowContext(ctx, query, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *UserIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.UserIdentity, error) {
+	query := `SELECT ` + userIdentityColumns + `
+              FROM user_identities
+              WHERE user_id = $1
+              ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*models.UserIdentity
+	for rows.Next() {
+		identity, err := scanUserIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *UserIdentityRepository) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	query := `UPDATE user_identities SET email = $1 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, email, id)
+	return err
+}
+
+// UpdateRefreshToken replaces the sealed refresh token, e.g. when the provider rotated it
+func (r *UserIdentityRepository) UpdateRefreshToken(ctx context.Context, id uuid.UUID, refreshToken string) error {
+	query := `UPDATE user_identities SET refresh_token = $1 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, refreshToken, id)
+	return err
+}
+
+// Delete unlinks an identity, reporting false if the user has no identity with that ID
+func (r *UserIdentityRepository) Delete(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	query := `DELETE FROM user_identities WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var userIdentityRowColumns = []string{"id", "user_id", "provider", "subject", "email", "created_at", "refresh_token"}
+
+func TestUserIdentityRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserIdentityRepository(db)
+	userID := uuid.New()
+
+	mock.ExpectExec("^INSERT INTO user_identities").
+		WithArgs(sqlmock.AnyArg(), userID, "google", "subject", "ada@example.com", sqlmock.AnyArg(), "sealed").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	identity := &models.UserIdentity{UserID: userID, Provider: "google", Subject: "subject", Email: "ada@example.com", RefreshToken: "sealed"}
+	assert.NoError(t, repo.Create(context.Background(), identity))
+	assert.NotEqual(t, uuid.Nil, identity.ID)
+	assert.False(t, identity.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserIdentityRepository_GetByProviderSubject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserIdentityRepository(db)
+	identityID, userID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *models.UserIdentity
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				rows := sqlmock.NewRows(userIdentityRowColumns).AddRow(identityID, userID, "google", "subject", "ada@example.com", now, "sealed")
+				mock.ExpectQuery("^SELECT (.+) FROM user_identities WHERE provider = \\$1 AND subject = \\$2").
+					WithArgs("google", "subject").
+					WillReturnRows(rows)
+			},
+			want:    &models.UserIdentity{ID: identityID, UserID: userID, Provider: "google", Subject: "subject", Email: "ada@example.com", CreatedAt: now, RefreshToken: "sealed"},
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM user_identities WHERE provider = \\$1 AND subject = \\$2").
+					WithArgs("google", "subject").
+					WillReturnError(sql.ErrNoRows)
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM user_identities WHERE provider = \\$1 AND subject = \\$2").
+					WithArgs("google", "subject").
+					WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.GetByProviderSubject(context.Background(), "google", "subject")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserIdentityRepository.GetByProviderSubject() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserIdentityRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserIdentityRepository(db)
+	userID := uuid.New()
+	now := time.Now()
+
+	rows := sqlmock.NewRows(userIdentityRowColumns).
+		AddRow(uuid.New(), userID, "google", "google-subject", "ada@example.com", now, "").
+		AddRow(uuid.New(), userID, "keycloak", "keycloak-subject", "ada@work.example", now, "")
+	mock.ExpectQuery("^SELECT (.+) FROM user_identities WHERE user_id = \\$1 ORDER BY created_at").
+		WithArgs(userID).
+		WillReturnRows(rows)
+
+	identities, err := repo.ListByUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 2)
+	assert.Equal(t, "keycloak", identities[1].Provider)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserIdentityRepository_UpdateAndDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserIdentityRepository(db)
+	identityID, userID := uuid.New(), uuid.New()
+
+	mock.ExpectExec("^UPDATE user_identities SET email").WithArgs("new@example.com", identityID).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdateEmail(context.Background(), identityID, "new@example.com"))
+
+	mock.ExpectExec("^UPDATE user_identities SET refresh_token").WithArgs("sealed", identityID).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdateRefreshToken(context.Background(), identityID, "sealed"))
+
+	mock.ExpectExec("^DELETE FROM user_identities WHERE id = \\$1 AND user_id = \\$2").WithArgs(identityID, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	deleted, err := repo.Delete(context.Background(), identityID, userID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	mock.ExpectExec("^DELETE FROM user_identities WHERE id = \\$1 AND user_id = \\$2").WithArgs(identityID, userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	deleted, err = repo.Delete(context.Background(), identityID, userID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
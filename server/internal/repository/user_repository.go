@@ -3,12 +3,22 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 )
 
+type UserPage struct {
+	Users           []*models.User
+	TotalCount      int
+	HasNextPage     bool
+	HasPreviousPage bool
+}
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -20,43 +30,217 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// Columns read by scanUser, in order
+const userColumns = `id, email, name, avatar_url, role, taste, rating_count, score_mean, score_m2, password_hash, email_verified_at, suspended_at, created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// uuidArray passes IDs as a Postgres array, for queries like "WHERE id = ANY($1::uuid[])"
+func uuidArray(ids []uuid.UUID) interface{} {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return pq.Array(strs)
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Name, &user.AvatarURL, &user.Role, &user.Taste,
+		&user.ScoreStats.Count, &user.ScoreStats.Mean, &user.ScoreStats.M2, &user.PasswordHash, &user.EmailVerifiedAt,
+		&user.SuspendedAt, &user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO users (id, email, name, role, taste, created_at) 
-              VALUES ($1, $2, $3, $4, $5, $6)`
+	query := `INSERT INTO users (id, email, name, avatar_url, role, taste, password_hash, email_verified_at, created_at) 
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	user.CreatedAt = time.Now()
 	user.Taste = pgvector.NewVector(make([]float32, 512))
 
 	_, err := r.db.ExecContext(ctx, query,
-		user.ID, user.Email, user.Name, user.Role, user.Taste, user.CreatedAt,
+		user.ID, user.Email, user.Name, user.AvatarURL, user.Role, user.Taste, user.PasswordHash, user.EmailVerifiedAt, user.CreatedAt,
 	)
 	return err
 }
 
+func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	query := `SELECT ` + userColumns + `
+              FROM users 
+              WHERE id = $1`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetByIDs returns the users with the given IDs in no particular order, skipping IDs that don't exist
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	query := `SELECT ` + userColumns + `
+              FROM users
+              WHERE id = ANY($1::uuid[])`
+
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, role, taste, created_at 
+	query := `SELECT ` + userColumns + `
               FROM users 
               WHERE email = $1`
 
-	var user models.User
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Role, &user.Taste, &user.CreatedAt,
-	)
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	user.Email = email
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `UPDATE users 
-              SET email = $1, role = $2, taste = $3
-              WHERE id = $4`
+              SET email = $1, name = $2, avatar_url = $3, role = $4, taste = $5, rating_count = $6, score_mean = $7, score_m2 = $8
+              WHERE id = $9`
+
+	_, err := r.db.ExecContext(ctx, query,
+		user.Email, user.Name, user.AvatarURL, user.Role, user.Taste,
+		user.ScoreStats.Count, user.ScoreStats.Mean, user.ScoreStats.M2, user.ID,
+	)
+	return err
+}
+
+func (r *UserRepository) Search(ctx context.Context, searchTerm string, page, pageSize int) (*UserPage, error) {
+	offset := (page - 1) * pageSize
+
+	query := `SELECT ` + userColumns + `
+              FROM users
+              WHERE $3 = '' OR email ILIKE '%' || $3 || '%' OR name ILIKE '%' || $3 || '%'
+              ORDER BY created_at DESC, id
+              LIMIT $1 OFFSET $2`
+
+	countQuery := `SELECT COUNT(*)
+              FROM users
+              WHERE $1 = '' OR email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%'`
+
+	rows, err := r.db.QueryContext(ctx, query, pageSize+1, offset, searchTerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var totalCount int
+	if err := r.db.QueryRowContext(ctx, countQuery, searchTerm).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	hasNextPage := len(users) > pageSize
+	if hasNextPage {
+		users = users[:pageSize]
+	}
+
+	return &UserPage{
+		Users:           users,
+		TotalCount:      totalCount,
+		HasNextPage:     hasNextPage,
+		HasPreviousPage: page > 1,
+	}, nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string) error {
+	query := `UPDATE users 
+              SET role = $1
+              WHERE id = $2`
 
-	_, err := r.db.ExecContext(ctx, query, user.Email, user.Role, user.Taste, user.ID)
+	_, err := r.db.ExecContext(ctx, query, role, id)
 	return err
 }
+
+// UpdateSuspension suspends the user at suspendedAt, or reactivates them when it is nil
+func (r *UserRepository) UpdateSuspension(ctx context.Context, id uuid.UUID, suspendedAt *time.Time) error {
+	query := `UPDATE users 
+              SET suspended_at = $1
+              WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, suspendedAt, id)
+	return err
+}
+
+// UpdatePassword replaces the password hash, an empty hash leaves the user without a password
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users 
+              SET password_hash = $1
+              WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	return err
+}
+
+// MarkEmailVerified records when the user proved owning their email, keeping the first time if it was already verified
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	query := `UPDATE users 
+              SET email_verified_at = COALESCE(email_verified_at, $1)
+              WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, verifiedAt, id)
+	return err
+}
+
+// Delete removes the user and reports whether the user existed.
+// Their ratings, sessions, access tokens and data exports are removed with them by ON DELETE CASCADE.
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
@@ -69,6 +69,106 @@ func TestUserRepository_Create(t *testing.T) {
 	}
 }
 
+var userRowColumns = []string{"id", "email", "name", "avatar_url", "role", "taste", "rating_count", "score_mean", "score_m2", "password_hash", "email_verified_at", "suspended_at", "created_at"}
+
+func TestUserRepository_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	tests := []struct {
+		name      string
+		id        uuid.UUID
+		mockSetup func(id uuid.UUID)
+		want      *models.User
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			id:   uuid.New(),
+			mockSetup: func(id uuid.UUID) {
+				rows := sqlmock.NewRows(userRowColumns).
+					AddRow(id, "test@example.com", "Test User", "https://example.com/a.png", "USER", pgvector.NewVector(make([]float32, 512)), 0, 0, 0, "", nil, nil, time.Now())
+				mock.ExpectQuery("^SELECT (.+) FROM users WHERE id").WithArgs(id).WillReturnRows(rows)
+			},
+			want:    &models.User{Email: "test@example.com", Name: "Test User", AvatarURL: "https://example.com/a.png"},
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			id:   uuid.New(),
+			mockSetup: func(id uuid.UUID) {
+				mock.ExpectQuery("^SELECT (.+) FROM users WHERE id").WillReturnError(sql.ErrNoRows)
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			id:   uuid.New(),
+			mockSetup: func(id uuid.UUID) {
+				mock.ExpectQuery("^SELECT (.+) FROM users WHERE id").WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup(tt.id)
+
+			got, err := repo.GetByID(context.Background(), tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserRepository.GetByID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.id, got.ID)
+			assert.Equal(t, tt.want.Email, got.Email)
+			assert.Equal(t, tt.want.Name, got.Name)
+			assert.Equal(t, tt.want.AvatarURL, got.AvatarURL)
+		})
+	}
+}
+
+func TestUserRepository_GetByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	now := time.Now()
+
+	rows := sqlmock.NewRows(userRowColumns).
+		AddRow(ids[0], "a@example.com", "A", "", "USER", pgvector.NewVector([]float32{0}), 0, 0, 0, "", now, nil, now).
+		AddRow(ids[1], "b@example.com", "B", "", "USER", pgvector.NewVector([]float32{0}), 0, 0, 0, "", now, nil, now)
+	mock.ExpectQuery("^SELECT (.+) FROM users WHERE id = ANY").
+		WithArgs(uuidArray(ids)).
+		WillReturnRows(rows)
+
+	users, err := repo.GetByIDs(context.Background(), ids)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "b@example.com", users[1].Email)
+
+	mock.ExpectQuery("^SELECT (.+) FROM users WHERE id = ANY").WillReturnError(sql.ErrConnDone)
+	_, err = repo.GetByIDs(context.Background(), ids)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUserRepository_GetByEmail(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -89,8 +189,8 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			name:  "Success",
 			email: "test@example.com",
 			mockSetup: func() {
-				rows := sqlmock.NewRows([]string{"id", "role", "taste", "created_at"}).
-					AddRow(uuid.New(), "user", pgvector.NewVector(make([]float32, 512)), time.Now())
+				rows := sqlmock.NewRows(userRowColumns).
+					AddRow(uuid.New(), "test@example.com", "Test User", "", "user", pgvector.NewVector(make([]float32, 512)), 3, 2.5, 1.5, "", nil, nil, time.Now())
 				mock.ExpectQuery("^SELECT (.+) FROM users WHERE").WillReturnRows(rows)
 			},
 			want:    &models.User{},
@@ -128,6 +228,7 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			if !tt.wantErr && got != nil {
 				assert.IsType(t, &models.User{}, got)
 				assert.Equal(t, tt.email, got.Email)
+				assert.Equal(t, "Test User", got.Name)
 			}
 		})
 	}
@@ -187,3 +288,165 @@ func TestUserRepository_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepository_Search(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	tests := []struct {
+		name       string
+		searchTerm string
+		pageSize   int
+		mockSetup  func()
+		want       *UserPage
+		wantErr    bool
+	}{
+		{
+			name:       "Success",
+			searchTerm: "example",
+			pageSize:   1,
+			mockSetup: func() {
+				suspendedAt := time.Now()
+				rows := sqlmock.NewRows(userRowColumns).
+					AddRow(uuid.New(), "a@example.com", "A", "", "USER", pgvector.NewVector(make([]float32, 512)), 0, 0, 0, "", nil, suspendedAt, time.Now()).
+					AddRow(uuid.New(), "b@example.com", "B", "", "ADMIN", pgvector.NewVector(make([]float32, 512)), 0, 0, 0, "", nil, nil, time.Now())
+				mock.ExpectQuery("^SELECT (.+) FROM users WHERE").WithArgs(2, 0, "example").WillReturnRows(rows)
+				mock.ExpectQuery("^SELECT COUNT").WithArgs("example").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			},
+			want:    &UserPage{TotalCount: 2, HasNextPage: true},
+			wantErr: false,
+		},
+		{
+			name:       "Error",
+			searchTerm: "",
+			pageSize:   10,
+			mockSetup: func() {
+				mock.ExpectQuery("^SELECT (.+) FROM users WHERE").WillReturnError(sql.ErrConnDone)
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.Search(context.Background(), tt.searchTerm, 1, tt.pageSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserRepository.Search() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.want.TotalCount, got.TotalCount)
+				assert.Equal(t, tt.want.HasNextPage, got.HasNextPage)
+				assert.Len(t, got.Users, tt.pageSize)
+				assert.NotNil(t, got.Users[0].SuspendedAt)
+			}
+		})
+	}
+}
+
+func TestUserRepository_UpdateRoleAndSuspension(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	id := uuid.New()
+	suspendedAt := time.Now()
+
+	mock.ExpectExec("^UPDATE users SET role").WithArgs("ADMIN", id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdateRole(context.Background(), id, "ADMIN"))
+
+	mock.ExpectExec("^UPDATE users SET suspended_at").WithArgs(&suspendedAt, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdateSuspension(context.Background(), id, &suspendedAt))
+
+	mock.ExpectExec("^UPDATE users SET suspended_at").WillReturnError(sql.ErrConnDone)
+	assert.Error(t, repo.UpdateSuspension(context.Background(), id, nil))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdatePasswordAndMarkEmailVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	id := uuid.New()
+	verifiedAt := time.Now()
+
+	mock.ExpectExec("^UPDATE users SET password_hash").WithArgs("$argon2id$hash", id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.UpdatePassword(context.Background(), id, "$argon2id$hash"))
+
+	mock.ExpectExec("^UPDATE users SET email_verified_at = COALESCE").WithArgs(verifiedAt, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.MarkEmailVerified(context.Background(), id, verifiedAt))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				mock.ExpectExec("^DELETE FROM users WHERE id").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mock.ExpectExec("^DELETE FROM users WHERE id").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mock.ExpectExec("^DELETE FROM users WHERE id").WillReturnError(sql.ErrConnDone)
+			},
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := repo.Delete(context.Background(), uuid.New())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserRepository.Delete() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// Makes personal access tokens easy to recognise, e.g. by secret scanners
+	accessTokenPrefix        = "nwpat_"
+	maxAccessTokenNameLength = 100
+)
+
+var ErrInvalidAccessToken = apperr.Unauthenticated("invalid, expired or revoked access token")
+
+var validScopes = map[string]bool{models.ScopeRead: true, models.ScopeWrite: true}
+
+type AccessTokenService struct {
+	tokenRepo repository.AccessTokenRepositoryInterface
+	userRepo  repository.UserRepositoryInterface
+}
+
+func NewAccessTokenService(tokenRepo repository.AccessTokenRepositoryInterface, userRepo repository.UserRepositoryInterface) *AccessTokenService {
+	return &AccessTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// CreateToken creates a personal access token and returns it in plain text.
+// This is the only time the token is available, only its hash is stored.
+func (s *AccessTokenService) CreateToken(ctx context.Context, user *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, apperr.Validation("token name is required")
+	}
+	if len(name) > maxAccessTokenNameLength {
+		return "", nil, apperr.Validation(fmt.Sprintf("token name cannot be longer than %d characters", maxAccessTokenNameLength))
+	}
+	if len(scopes) == 0 {
+		return "", nil, apperr.Validation("at least one scope is required")
+	}
+	uniqueScopes := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !validScopes[scope] {
+			return "", nil, apperr.Validation(fmt.Sprintf("unknown scope %q", scope))
+		}
+		uniqueScopes[scope] = true
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, apperr.Validation("expiry must be in the future")
+	}
+
+	tokenScopes := make([]string, 0, len(uniqueScopes))
+	for scope := range uniqueScopes {
+		tokenScopes = append(tokenScopes, scope)
+	}
+	sort.Strings(tokenScopes)
+
+	plainToken, err := newToken(accessTokenPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	token := &models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: hashToken(plainToken),
+		Scopes:    tokenScopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+	return plainToken, token, nil
+}
+
+// Authenticate returns the user and token record for a plain text token, or ErrInvalidAccessToken
+// if it is unknown, revoked or expired. The token's last used time is recorded.
+func (s *AccessTokenService) Authenticate(ctx context.Context, plainToken string) (*models.User, *models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(plainToken, accessTokenPrefix) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	token, err := s.tokenRepo.GetByTokenHash(ctx, hashToken(plainToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if token == nil || token.RevokedAt != nil || (token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= sessionTouchInterval {
+		if err := s.tokenRepo.UpdateLastUsed(ctx, token.ID, now); err != nil {
+			return nil, nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return user, token, nil
+}
+
+// ListTokens returns the user's tokens that haven't been revoked, including expired ones
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+// RevokeToken reports false if the token doesn't exist, belongs to someone else or was already revoked
+func (s *AccessTokenService) RevokeToken(ctx context.Context, userID, tokenID uuid.UUID) (bool, error) {
+	return s.tokenRepo.Revoke(ctx, tokenID, userID, time.Now())
+}
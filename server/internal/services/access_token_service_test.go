@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccessTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepository) GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID, lastUsedAt time.Time) error {
+	args := m.Called(ctx, id, lastUsedAt)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepository) Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, userID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestAccessTokenService_CreateToken(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New()}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		tokenName  string
+		scopes     []string
+		expiresAt  *time.Time
+		wantScopes []string
+		wantErr    bool
+	}{
+		{
+			name:       "Success",
+			tokenName:  "  nightly sync ",
+			scopes:     []string{"write", "read", "write"},
+			wantScopes: []string{"read", "write"},
+			wantErr:    false,
+		},
+		{
+			name:      "Error - Missing Name",
+			tokenName: " ",
+			scopes:    []string{"read"},
+			wantErr:   true,
+		},
+		{
+			name:      "Error - No Scopes",
+			tokenName: "ci",
+			scopes:    nil,
+			wantErr:   true,
+		},
+		{
+			name:      "Error - Unknown Scope",
+			tokenName: "ci",
+			scopes:    []string{"admin"},
+			wantErr:   true,
+		},
+		{
+			name:      "Error - Expired",
+			tokenName: "ci",
+			scopes:    []string{"read"},
+			expiresAt: &past,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokenRepo := new(MockAccessTokenRepository)
+			mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*models.PersonalAccessToken")).Return(nil)
+			service := NewAccessTokenService(mockTokenRepo, nil)
+
+			plainToken, token, err := service.CreateToken(ctx, user, tt.tokenName, tt.scopes, tt.expiresAt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AccessTokenService.CreateToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			assert.True(t, strings.HasPrefix(plainToken, accessTokenPrefix))
+			assert.Equal(t, hashToken(plainToken), token.TokenHash)
+			assert.Equal(t, "nightly sync", token.Name)
+			assert.Equal(t, tt.wantScopes, token.Scopes)
+		})
+	}
+}
+
+func TestAccessTokenService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New()}
+	plainToken := accessTokenPrefix + "secret"
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		plainToken string
+		token      *models.PersonalAccessToken
+		wantTouch  bool
+		wantErr    error
+	}{
+		{
+			name:       "Success - First Use",
+			plainToken: plainToken,
+			token:      &models.PersonalAccessToken{ID: uuid.New(), UserID: user.ID},
+			wantTouch:  true,
+			wantErr:    nil,
+		},
+		{
+			name:       "Success - Recently Used",
+			plainToken: plainToken,
+			token:      &models.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, LastUsedAt: &now},
+			wantTouch:  false,
+			wantErr:    nil,
+		},
+		{
+			name:       "Error - Not An Access Token",
+			plainToken: "secret",
+			wantErr:    ErrInvalidAccessToken,
+		},
+		{
+			name:       "Error - Unknown",
+			plainToken: plainToken,
+			token:      nil,
+			wantErr:    ErrInvalidAccessToken,
+		},
+		{
+			name:       "Error - Revoked",
+			plainToken: plainToken,
+			token:      &models.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, RevokedAt: &past},
+			wantErr:    ErrInvalidAccessToken,
+		},
+		{
+			name:       "Error - Expired",
+			plainToken: plainToken,
+			token:      &models.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, ExpiresAt: &past},
+			wantErr:    ErrInvalidAccessToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokenRepo := new(MockAccessTokenRepository)
+			mockUserRepo := new(MockUserRepository)
+			service := NewAccessTokenService(mockTokenRepo, mockUserRepo)
+			if tt.token != nil {
+				mockTokenRepo.On("GetByTokenHash", ctx, hashToken(plainToken)).Return(tt.token, nil)
+			} else {
+				mockTokenRepo.On("GetByTokenHash", ctx, hashToken(plainToken)).Return(nil, nil)
+			}
+			mockUserRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+			if tt.wantTouch {
+				mockTokenRepo.On("UpdateLastUsed", ctx, tt.token.ID, mock.AnythingOfType("time.Time")).Return(nil).Once()
+			}
+
+			got, token, err := service.Authenticate(ctx, tt.plainToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AccessTokenService.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				assert.Equal(t, user, got)
+				assert.NotNil(t, token.LastUsedAt)
+			}
+			if !tt.wantTouch {
+				mockTokenRepo.AssertNotCalled(t, "UpdateLastUsed", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/mailer"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/policy"
+	"github.com/Azanul/Next-Watch/internal/ratelimit"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	minPasswordLength = 8
+	// argon2 accepts any length, but the client shouldn't decide how much we hash
+	maxPasswordLength = 128
+
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	signInAttemptsPerEmail = 5
+	signInAttemptsPerIP    = 20
+	signInAttemptWindow    = 15 * time.Minute
+
+	// Sign-ups and reset requests email whoever owns the address, limited so nobody can flood a mailbox
+	emailsPerAddress = 3
+	emailWindow      = time.Hour
+)
+
+var (
+	ErrInvalidCredentials  = apperr.Unauthenticated("invalid email or password")
+	ErrEmailNotVerified    = apperr.Forbidden("email address is not verified, a new verification link was sent")
+	ErrInvalidAccountToken = apperr.Validation("invalid, expired or already used link")
+)
+
+// RateLimitError is returned when an email address or IP made too many attempts
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Verified against when the email is unknown, so failed sign-ins take as long whether or not the account exists
+var dummyPasswordHash, _ = auth.HashPassword("dummy password")
+
+// AccountService handles users who sign in with an email and password instead of a provider
+type AccountService struct {
+	userRepo         repository.UserRepositoryInterface
+	accountTokenRepo repository.AccountTokenRepositoryInterface
+	mailer           mailer.Mailer
+	publicURL        string
+	emailLimiter     *ratelimit.Limiter
+	ipLimiter        *ratelimit.Limiter
+	// Separate from emailLimiter, so emailing an address doesn't lock its owner out of signing in
+	mailLimiter *ratelimit.Limiter
+}
+
+// NewAccountService creates the service. Links in emails point to publicURL, e.g. "https://nextwatch.example".
+func NewAccountService(userRepo repository.UserRepositoryInterface, accountTokenRepo repository.AccountTokenRepositoryInterface, m mailer.Mailer, publicURL string) *AccountService {
+	return &AccountService{
+		userRepo:         userRepo,
+		accountTokenRepo: accountTokenRepo,
+		mailer:           m,
+		publicURL:        strings.TrimSuffix(publicURL, "/"),
+		emailLimiter:     ratelimit.New(signInAttemptsPerEmail, signInAttemptWindow),
+		ipLimiter:        ratelimit.New(signInAttemptsPerIP, signInAttemptWindow),
+		mailLimiter:      ratelimit.New(emailsPerAddress, emailWindow),
+	}
+}
+
+// SignUp creates an account with a password and emails a link to verify the address.
+// The account can't sign in until the address is verified. If the address already has an account,
+// its owner is emailed instead and the caller gets the same answer, so sign-up doesn't reveal which
+// addresses have accounts. Attempts are limited per IP address like sign-ins, and per email address.
+func (s *AccountService) SignUp(ctx context.Context, email, password, name, ipAddress string) error {
+	if allowed, retryAfter := s.ipLimiter.Allow(ipAddress); !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	if allowed, retryAfter := s.mailLimiter.Allow(email); !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	// Hashed before the lookup so both answers take as long
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return s.mailer.Send(ctx, mailer.Message{
+			To:      existing.Email,
+			Subject: "You already have a Next Watch account",
+			Body: "Someone tried to sign up for Next Watch with this email address, which already has an account. " +
+				"If it was you, sign in instead, or reset your password if you forgot it:\n\n" +
+				s.publicURL + "/\n\nIf it wasn't you, you can ignore this email.",
+		})
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	user := &models.User{
+		ID:           uuid.New(),
+		Email:        email,
+		Name:         name,
+		Role:         string(policy.RoleUser),
+		PasswordHash: passwordHash,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// SignIn checks an email and password. Attempts are limited per email and per IP address.
+func (s *AccountService) SignIn(ctx context.Context, email, password, ipAddress string) (*models.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if allowed, retryAfter := s.ipLimiter.Allow(ipAddress); !allowed {
+		return nil, &RateLimitError{RetryAfter: retryAfter}
+	}
+	if allowed, retryAfter := s.emailLimiter.Allow(email); !allowed {
+		return nil, &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.PasswordHash == "" {
+		auth.VerifyPassword(dummyPasswordHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	ok, err := auth.VerifyPassword(user.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	s.emailLimiter.Reset(email)
+
+	// Only reported after the password matched, so it doesn't reveal which addresses have accounts
+	if user.EmailVerifiedAt == nil {
+		if err := s.sendVerification(ctx, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
+
+// VerifyEmail redeems the link sent by SignUp
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	accountToken, err := s.redeem(ctx, token, models.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.MarkEmailVerified(ctx, accountToken.UserID, time.Now())
+}
+
+// RequestPasswordReset emails a reset link if an account uses the address. Unknown addresses
+// are ignored without an error so the response doesn't reveal which addresses have accounts.
+// Users who only signed in with a provider can use it to set a password.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email, ipAddress string) error {
+	if allowed, retryAfter := s.ipLimiter.Allow(ipAddress); !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	// Limited whether or not the address has an account, so the limit doesn't reveal it either
+	if allowed, retryAfter := s.mailLimiter.Allow(email); !allowed {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user == nil {
+		return err
+	}
+
+	link, err := s.createLink(ctx, user, models.PurposeResetPassword, passwordResetTTL, "/auth/local/reset")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Next Watch password",
+		Body: "Someone asked to reset the password of your Next Watch account. If it was you, choose a new password here:\n\n" +
+			link + "\n\nThe link expires in an hour. If it wasn't you, you can ignore this email.",
+	})
+}
+
+// ResetPassword sets a new password with the link from RequestPasswordReset and signs the user out everywhere,
+// revoking their personal access tokens too. Receiving the link proves owning the address, so it also verifies it.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	// Checked first so a rejected password doesn't use up the link
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrInvalidAccountToken
+	}
+
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	reset, err := s.accountTokenRepo.ResetPassword(ctx, hashToken(token), passwordHash, time.Now())
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return ErrInvalidAccountToken
+	}
+	return nil
+}
+
+// ClaimUnverifiedAccount is called when a provider vouches for the email of an account that was signed up
+// with a password but never verified. The password could have been set by anyone, so it is dropped.
+func (s *AccountService) ClaimUnverifiedAccount(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	if user.PasswordHash != "" {
+		if err := s.userRepo.UpdatePassword(ctx, user.ID, ""); err != nil {
+			return err
+		}
+		user.PasswordHash = ""
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return err
+	}
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+func (s *AccountService) sendVerification(ctx context.Context, user *models.User) error {
+	link, err := s.createLink(ctx, user, models.PurposeVerifyEmail, emailVerificationTTL, "/auth/local/verify")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email for Next Watch",
+		Body:    "Welcome to Next Watch! Open this link to verify your email address and sign in:\n\n" + link + "\n\nThe link expires in 48 hours.",
+	})
+}
+
+// createLink stores a one-time token and returns the link that redeems it
+func (s *AccountService) createLink(ctx context.Context, user *models.User, purpose string, ttl time.Duration, path string) (string, error) {
+	token, err := newToken("")
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.accountTokenRepo.Create(ctx, &models.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.publicURL + path + "?" + url.Values{"token": {token}}.Encode(), nil
+}
+
+func (s *AccountService) redeem(ctx context.Context, token, purpose string) (*models.AccountToken, error) {
+	if token == "" {
+		return nil, ErrInvalidAccountToken
+	}
+	accountToken, err := s.accountTokenRepo.Redeem(ctx, hashToken(token), purpose, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if accountToken == nil {
+		return nil, ErrInvalidAccountToken
+	}
+	return accountToken, nil
+}
+
+// normalizeEmail validates a bare address such as "someone@example.com" and lowercases it
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", apperr.Validation("invalid email address")
+	}
+	return strings.ToLower(email), nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return apperr.Validation(fmt.Sprintf("password must be at least %d characters long", minPasswordLength))
+	}
+	if len(password) > maxPasswordLength {
+		return apperr.Validation(fmt.Sprintf("password must be at most %d characters long", maxPasswordLength))
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/mailer"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAccountTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountTokenRepository) Create(ctx context.Context, token *models.AccountToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAccountTokenRepository) Redeem(ctx context.Context, tokenHash []byte, purpose string, now time.Time) (*models.AccountToken, error) {
+	args := m.Called(ctx, tokenHash, purpose, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountToken), args.Error(1)
+}
+
+func (m *MockAccountTokenRepository) ResetPassword(ctx context.Context, tokenHash []byte, passwordHash string, now time.Time) (bool, error) {
+	args := m.Called(ctx, tokenHash, passwordHash, now)
+	return args.Bool(0), args.Error(1)
+}
+
+// recordingMailer keeps sent emails so tests can follow their links
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// tokenFromLink pulls the token out of the last link sent
+func (m *recordingMailer) tokenFromLink(t *testing.T) string {
+	require.NotEmpty(t, m.sent)
+	body := m.sent[len(m.sent)-1].Body
+	start := strings.Index(body, "https://nextwatch.example/")
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func newTestAccountService() (*AccountService, *MockUserRepository, *MockAccountTokenRepository, *recordingMailer) {
+	userRepo := new(MockUserRepository)
+	accountTokenRepo := new(MockAccountTokenRepository)
+	m := &recordingMailer{}
+	return NewAccountService(userRepo, accountTokenRepo, m, "https://nextwatch.example/"), userRepo, accountTokenRepo, m
+}
+
+func TestAccountService_SignUp(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		mockSetup func(userRepo *MockUserRepository, accountTokenRepo *MockAccountTokenRepository)
+		wantErr   error
+		wantMail  string
+	}{
+		{
+			name:     "Success",
+			email:    " New@Example.com ",
+			password: "correct horse",
+			mockSetup: func(userRepo *MockUserRepository, accountTokenRepo *MockAccountTokenRepository) {
+				userRepo.On("GetByEmail", ctx, "new@example.com").Return(nil, nil)
+				userRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+				accountTokenRepo.On("Create", ctx, mock.MatchedBy(func(token *models.AccountToken) bool {
+					return token.Purpose == models.PurposeVerifyEmail
+				})).Return(nil)
+			},
+			wantMail: "Verify your email for Next Watch",
+		},
+		{
+			name:      "Invalid Email",
+			email:     "Someone <someone@example.com>",
+			password:  "correct horse",
+			mockSetup: func(userRepo *MockUserRepository, accountTokenRepo *MockAccountTokenRepository) {},
+			wantErr:   apperr.Validation("invalid email address"),
+		},
+		{
+			name:      "Short Password",
+			email:     "new@example.com",
+			password:  "short",
+			mockSetup: func(userRepo *MockUserRepository, accountTokenRepo *MockAccountTokenRepository) {},
+			wantErr:   apperr.Validation("password must be at least 8 characters long"),
+		},
+		{
+			name:     "Email Taken Emails The Owner",
+			email:    "taken@example.com",
+			password: "correct horse",
+			mockSetup: func(userRepo *MockUserRepository, accountTokenRepo *MockAccountTokenRepository) {
+				userRepo.On("GetByEmail", ctx, "taken@example.com").Return(&models.User{ID: uuid.New(), Email: "taken@example.com"}, nil)
+			},
+			wantMail: "You already have a Next Watch account",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, userRepo, accountTokenRepo, m := newTestAccountService()
+			tt.mockSetup(userRepo, accountTokenRepo)
+
+			err := service.SignUp(ctx, tt.email, tt.password, "", "127.0.0.1")
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+				assert.Empty(t, m.sent)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, m.sent, 1)
+			assert.Equal(t, tt.wantMail, m.sent[0].Subject)
+			userRepo.AssertExpectations(t)
+			accountTokenRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAccountService_SignUp_CreatesUnverifiedUser(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, accountTokenRepo, m := newTestAccountService()
+	userRepo.On("GetByEmail", ctx, "new@example.com").Return(nil, nil)
+	var user *models.User
+	userRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		user = args.Get(1).(*models.User)
+	}).Return(nil)
+	accountTokenRepo.On("Create", ctx, mock.AnythingOfType("*models.AccountToken")).Return(nil)
+
+	require.NoError(t, service.SignUp(ctx, "new@example.com", "correct horse", "", "127.0.0.1"))
+	require.NotNil(t, user)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "new", user.Name)
+	assert.Equal(t, "USER", user.Role)
+	assert.Nil(t, user.EmailVerifiedAt)
+	ok, err := auth.VerifyPassword(user.PasswordHash, "correct horse")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "new@example.com", m.sent[0].To)
+	assert.NotEmpty(t, m.tokenFromLink(t))
+}
+
+func TestAccountService_SignUp_RateLimited(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, _, m := newTestAccountService()
+	userRepo.On("GetByEmail", ctx, mock.Anything).Return(&models.User{ID: uuid.New(), Email: "target@example.com"}, nil)
+
+	for i := 0; i < emailsPerAddress; i++ {
+		assert.NoError(t, service.SignUp(ctx, "target@example.com", "correct horse", "", "10.0.0.1"))
+	}
+
+	// Probing the same address from elsewhere is limited too, and doesn't flood its owner with email
+	err := service.SignUp(ctx, "target@example.com", "correct horse", "", "10.0.0.2")
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Len(t, m.sent, emailsPerAddress)
+
+	// Sign-ups don't count against signing in to the address
+	for i := 0; i < signInAttemptsPerEmail; i++ {
+		_, err = service.SignIn(ctx, "target@example.com", "guess", "10.0.0.3")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+}
+
+func TestAccountService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	passwordHash, err := auth.HashPassword("correct horse")
+	require.NoError(t, err)
+	verifiedAt := time.Now()
+	verified := &models.User{ID: uuid.New(), Email: "verified@example.com", PasswordHash: passwordHash, EmailVerifiedAt: &verifiedAt}
+	unverified := &models.User{ID: uuid.New(), Email: "unverified@example.com", PasswordHash: passwordHash}
+	providerOnly := &models.User{ID: uuid.New(), Email: "google@example.com", EmailVerifiedAt: &verifiedAt}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantUser *models.User
+		wantErr  error
+		wantSent int
+	}{
+		{name: "Success", email: "Verified@example.com", password: "correct horse", wantUser: verified},
+		{name: "Wrong Password", email: "verified@example.com", password: "wrong horse", wantErr: ErrInvalidCredentials},
+		{name: "Unknown Email", email: "nobody@example.com", password: "correct horse", wantErr: ErrInvalidCredentials},
+		{name: "No Password", email: "google@example.com", password: "correct horse", wantErr: ErrInvalidCredentials},
+		{name: "Unverified Resends Link", email: "unverified@example.com", password: "correct horse", wantErr: ErrEmailNotVerified, wantSent: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, userRepo, accountTokenRepo, m := newTestAccountService()
+			userRepo.On("GetByEmail", ctx, verified.Email).Return(verified, nil)
+			userRepo.On("GetByEmail", ctx, unverified.Email).Return(unverified, nil)
+			userRepo.On("GetByEmail", ctx, providerOnly.Email).Return(providerOnly, nil)
+			userRepo.On("GetByEmail", ctx, mock.Anything).Return(nil, nil)
+			accountTokenRepo.On("Create", ctx, mock.AnythingOfType("*models.AccountToken")).Return(nil)
+
+			user, err := service.SignIn(ctx, tt.email, tt.password, "127.0.0.1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantUser, user)
+			assert.Len(t, m.sent, tt.wantSent)
+		})
+	}
+}
+
+func TestAccountService_SignIn_RateLimited(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, _, _ := newTestAccountService()
+	userRepo.On("GetByEmail", ctx, mock.Anything).Return(nil, nil)
+
+	for i := 0; i < signInAttemptsPerEmail; i++ {
+		_, err := service.SignIn(ctx, "target@example.com", "guess", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// The limit follows the email across addresses
+	_, err := service.SignIn(ctx, "target@example.com", "guess", "10.0.0.2")
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Greater(t, rateLimitErr.RetryAfter, time.Duration(0))
+
+	// Other emails are unaffected
+	_, err = service.SignIn(ctx, "other@example.com", "guess", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, accountTokenRepo, _ := newTestAccountService()
+	userID := uuid.New()
+
+	accountTokenRepo.On("Redeem", ctx, hashToken("valid"), models.PurposeVerifyEmail, mock.AnythingOfType("time.Time")).Return(&models.AccountToken{UserID: userID}, nil)
+	accountTokenRepo.On("Redeem", ctx, hashToken("used"), models.PurposeVerifyEmail, mock.AnythingOfType("time.Time")).Return(nil, nil)
+	userRepo.On("MarkEmailVerified", ctx, userID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, service.VerifyEmail(ctx, "valid"))
+	assert.ErrorIs(t, service.VerifyEmail(ctx, "used"), ErrInvalidAccountToken)
+	assert.ErrorIs(t, service.VerifyEmail(ctx, ""), ErrInvalidAccountToken)
+	userRepo.AssertNumberOfCalls(t, "MarkEmailVerified", 1)
+}
+
+func TestAccountService_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, accountTokenRepo, m := newTestAccountService()
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+
+	userRepo.On("GetByEmail", ctx, "test@example.com").Return(user, nil)
+	userRepo.On("GetByEmail", ctx, "nobody@example.com").Return(nil, nil)
+	accountTokenRepo.On("Create", ctx, mock.AnythingOfType("*models.AccountToken")).Return(nil)
+
+	// Unknown addresses look the same to the caller but get no email
+	assert.NoError(t, service.RequestPasswordReset(ctx, "nobody@example.com", "127.0.0.1"))
+	assert.Empty(t, m.sent)
+
+	assert.NoError(t, service.RequestPasswordReset(ctx, "test@example.com", "127.0.0.1"))
+	token := m.tokenFromLink(t)
+
+	// A rejected password doesn't use up the link
+	assert.Error(t, service.ResetPassword(ctx, token, "short"))
+	accountTokenRepo.AssertNotCalled(t, "ResetPassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	accountTokenRepo.On("ResetPassword", ctx, hashToken(token), mock.MatchedBy(func(hash string) bool {
+		ok, _ := auth.VerifyPassword(hash, "new password")
+		return ok
+	}), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+	assert.NoError(t, service.ResetPassword(ctx, token, "new password"))
+
+	accountTokenRepo.On("ResetPassword", ctx, hashToken(token), mock.Anything, mock.AnythingOfType("time.Time")).Return(false, nil)
+	assert.ErrorIs(t, service.ResetPassword(ctx, token, "new password"), ErrInvalidAccountToken)
+	assert.ErrorIs(t, service.ResetPassword(ctx, "", "new password"), ErrInvalidAccountToken)
+	accountTokenRepo.AssertExpectations(t)
+}
+
+func TestAccountService_RequestPasswordReset_RateLimited(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, accountTokenRepo, m := newTestAccountService()
+	userRepo.On("GetByEmail", ctx, mock.Anything).Return(&models.User{ID: uuid.New(), Email: "target@example.com"}, nil)
+	accountTokenRepo.On("Create", ctx, mock.AnythingOfType("*models.AccountToken")).Return(nil)
+
+	// Each request comes from a different address, so only the per-email limit applies
+	for i := 0; i < emailsPerAddress; i++ {
+		assert.NoError(t, service.RequestPasswordReset(ctx, "target@example.com", fmt.Sprintf("10.0.0.%d", i)))
+	}
+
+	err := service.RequestPasswordReset(ctx, "Target@example.com", "10.0.1.1")
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Len(t, m.sent, emailsPerAddress)
+}
+
+func TestAccountService_ClaimUnverifiedAccount(t *testing.T) {
+	ctx := context.Background()
+	service, userRepo, _, _ := newTestAccountService()
+	user := &models.User{ID: uuid.New(), Email: "test@example.com", PasswordHash: "$argon2id$set-by-someone-else"}
+
+	userRepo.On("UpdatePassword", ctx, user.ID, "").Return(nil)
+	userRepo.On("MarkEmailVerified", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, service.ClaimUnverifiedAccount(ctx, user))
+	assert.Empty(t, user.PasswordHash)
+	assert.NotNil(t, user.EmailVerifiedAt)
+	userRepo.AssertExpectations(t)
+}
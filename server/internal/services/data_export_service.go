@@ -0,0 +1,170 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+// How long the link to a requested archive stays valid
+const dataExportTTL = 24 * time.Hour
+
+var ErrInvalidDataExport = apperr.NotFound("invalid, expired or already used export link")
+
+// DataExportService answers access requests with an archive of everything stored about a user
+type DataExportService struct {
+	exportService   *ExportService
+	userRepo        repository.UserRepositoryInterface
+	sessionRepo     repository.SessionRepositoryInterface
+	accessTokenRepo repository.AccessTokenRepositoryInterface
+	identityRepo    repository.UserIdentityRepositoryInterface
+	dataExportRepo  repository.DataExportRepositoryInterface
+}
+
+func NewDataExportService(exportService *ExportService, userRepo repository.UserRepositoryInterface, sessionRepo repository.SessionRepositoryInterface, accessTokenRepo repository.AccessTokenRepositoryInterface, identityRepo repository.UserIdentityRepositoryInterface, dataExportRepo repository.DataExportRepositoryInterface) *DataExportService {
+	return &DataExportService{
+		exportService:   exportService,
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		accessTokenRepo: accessTokenRepo,
+		identityRepo:    identityRepo,
+		dataExportRepo:  dataExportRepo,
+	}
+}
+
+// ExportedProfile is everything stored on the user record itself
+type ExportedProfile struct {
+	ID          uuid.UUID         `json:"id"`
+	Email       string            `json:"email"`
+	Name        string            `json:"name"`
+	AvatarURL   string            `json:"avatarUrl"`
+	Role        string            `json:"role"`
+	Taste       []float32         `json:"taste"`
+	ScoreStats  models.ScoreStats `json:"scoreStats"`
+	SuspendedAt *time.Time        `json:"suspendedAt,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+// RequestArchive creates a one-time download link for the user's archive and returns its token.
+// The archive is built when the link is used, so it is never stored.
+func (s *DataExportService) RequestArchive(ctx context.Context, user *models.User) (string, *models.DataExport, error) {
+	token, err := newToken("")
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	export := &models.DataExport{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(dataExportTTL),
+	}
+	if err := s.dataExportRepo.Create(ctx, export); err != nil {
+		return "", nil, err
+	}
+	return token, export, nil
+}
+
+// RedeemArchive returns the user a download link was issued for. The link cannot be used again.
+func (s *DataExportService) RedeemArchive(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidDataExport
+	}
+
+	export, err := s.dataExportRepo.Redeem(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrInvalidDataExport
+	}
+
+	user, err := s.userRepo.GetByID(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidDataExport
+	}
+	return user, nil
+}
+
+// WriteArchive writes a zip archive with the user's profile, ratings, sessions and access tokens.
+// Token hashes are left out, they are of no use to the user.
+func (s *DataExportService) WriteArchive(ctx context.Context, user *models.User, w io.Writer) error {
+	sessions, err := s.sessionRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	accessTokens, err := s.accessTokenRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.identityRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	taste := user.Taste.Slice()
+	if taste == nil {
+		taste = []float32{}
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+	if accessTokens == nil {
+		accessTokens = []*models.PersonalAccessToken{}
+	}
+	if identities == nil {
+		identities = []*models.UserIdentity{}
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"profile.json", func(w io.Writer) error {
+			return writeIndentedJSON(w, ExportedProfile{
+				ID:          user.ID,
+				Email:       user.Email,
+				Name:        user.Name,
+				AvatarURL:   user.AvatarURL,
+				Role:        user.Role,
+				Taste:       taste,
+				ScoreStats:  user.ScoreStats,
+				SuspendedAt: user.SuspendedAt,
+				CreatedAt:   user.CreatedAt,
+			})
+		}},
+		{"ratings.csv", func(w io.Writer) error { return s.exportService.WriteCSV(ctx, user, w) }},
+		{"ratings.json", func(w io.Writer) error { return s.exportService.WriteJSON(ctx, user, w) }},
+		{"sessions.json", func(w io.Writer) error { return writeIndentedJSON(w, sessions) }},
+		{"access_tokens.json", func(w io.Writer) error { return writeIndentedJSON(w, accessTokens) }},
+		{"identities.json", func(w io.Writer) error { return writeIndentedJSON(w, identities) }},
+	}
+	for _, file := range files {
+		fw, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if err := file.write(fw); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeIndentedJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	args := m.Called(ctx, export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) Redeem(ctx context.Context, tokenHash []byte, now time.Time) (*models.DataExport, error) {
+	args := m.Called(ctx, tokenHash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func TestDataExportService_RequestAndRedeemArchive(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New()}
+	mockUserRepo := new(MockUserRepository)
+	mockDataExportRepo := new(MockDataExportRepository)
+	service := NewDataExportService(nil, mockUserRepo, nil, nil, nil, mockDataExportRepo)
+
+	mockDataExportRepo.On("Create", ctx, mock.AnythingOfType("*models.DataExport")).Return(nil)
+	token, export, err := service.RequestArchive(ctx, user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashToken(token), export.TokenHash)
+	assert.Equal(t, export.CreatedAt.Add(dataExportTTL), export.ExpiresAt)
+
+	mockDataExportRepo.On("Redeem", ctx, hashToken(token), mock.AnythingOfType("time.Time")).Return(export, nil).Once()
+	mockUserRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	redeemed, err := service.RedeemArchive(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, user, redeemed)
+
+	// Used, expired and unknown links all look the same
+	mockDataExportRepo.On("Redeem", ctx, hashToken(token), mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+	_, err = service.RedeemArchive(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidDataExport)
+
+	_, err = service.RedeemArchive(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidDataExport)
+}
+
+func TestDataExportService_WriteArchive(t *testing.T) {
+	ctx := context.Background()
+	user, ratedMovies := exportFixture()
+	mockRatingRepo := new(MockRatingRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	mockAccessTokenRepo := new(MockAccessTokenRepository)
+	mockIdentityRepo := new(MockUserIdentityRepository)
+	service := NewDataExportService(NewExportService(mockRatingRepo), nil, mockSessionRepo, mockAccessTokenRepo, mockIdentityRepo, nil)
+
+	mockRatingRepo.On("ForEachByUser", ctx, user.ID).Return(ratedMovies, nil)
+	mockSessionRepo.On("ListByUser", ctx, user.ID).Return([]*models.Session{{ID: uuid.New(), UserID: user.ID, TokenHash: []byte("secret"), UserAgent: "Firefox"}}, nil)
+	mockAccessTokenRepo.On("ListByUser", ctx, user.ID).Return(nil, nil)
+	mockIdentityRepo.On("ListByUser", ctx, user.ID).Return([]*models.UserIdentity{{ID: uuid.New(), UserID: user.ID, Provider: "google", Subject: "subject"}}, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, service.WriteArchive(ctx, user, &buf))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		assert.NoError(t, err)
+		files[file.Name], err = io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+	}
+	assert.Len(t, files, 6)
+
+	var profile ExportedProfile
+	assert.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, user.Email, profile.Email)
+	assert.Equal(t, []float32{0.6, 0.8}, profile.Taste)
+
+	assert.Contains(t, string(files["ratings.csv"]), "The Matrix")
+	assert.Contains(t, string(files["sessions.json"]), "Firefox")
+	assert.NotContains(t, string(files["sessions.json"]), "secret")
+	assert.JSONEq(t, "[]", string(files["access_tokens.json"]))
+	assert.Contains(t, string(files["identities.json"]), "google")
+}
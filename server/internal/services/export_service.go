@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+type ExportService struct {
+	ratingRepo repository.RatingRepositoryInterface
+}
+
+func NewExportService(ratingRepo repository.RatingRepositoryInterface) *ExportService {
+	return &ExportService{
+		ratingRepo: ratingRepo,
+	}
+}
+
+// ExportedUser is the user part of a JSON export
+type ExportedUser struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportedRating is a single rating of a JSON export
+type ExportedRating struct {
+	ID        uuid.UUID `json:"id"`
+	MovieID   uuid.UUID `json:"movieId"`
+	Title     string    `json:"title"`
+	Year      int       `json:"year"`
+	Score     float32   `json:"score"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WriteCSV writes the user's ratings in the format of Letterboxd's ratings.csv,
+// which both Letterboxd and ImportService accept back
+func (s *ExportService) WriteCSV(ctx context.Context, user *models.User, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Date", "Name", "Year", "Letterboxd URI", "Rating"}); err != nil {
+		return err
+	}
+
+	err := s.ratingRepo.ForEachByUser(ctx, user.ID, func(rm *repository.RatedMovie) error {
+		return writer.Write([]string{
+			rm.Rating.UpdatedAt.Format(time.DateOnly),
+			rm.Movie.Title,
+			strconv.Itoa(rm.Movie.Year),
+			"",
+			letterboxdRating(rm.Rating.Score),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the user, their taste vector and every rating as one JSON document.
+// Ratings are encoded one at a time so large accounts are never held in memory.
+func (s *ExportService) WriteJSON(ctx context.Context, user *models.User, w io.Writer) error {
+	exportedUser, err := json.Marshal(ExportedUser{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	taste := user.Taste.Slice()
+	if taste == nil {
+		taste = []float32{}
+	}
+	exportedTaste, err := json.Marshal(taste)
+	if err != nil {
+		return err
+	}
+
+	if err := writeAll(w, `{"user":`, string(exportedUser), `,"taste":`, string(exportedTaste), `,"ratings":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = s.ratingRepo.ForEachByUser(ctx, user.ID, func(rm *repository.RatedMovie) error {
+		exportedRating, err := json.Marshal(ExportedRating{
+			ID:        rm.Rating.ID,
+			MovieID:   rm.Rating.MovieID,
+			Title:     rm.Movie.Title,
+			Year:      rm.Movie.Year,
+			Score:     rm.Rating.Score,
+			CreatedAt: rm.Rating.CreatedAt,
+			UpdatedAt: rm.Rating.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		separator := ","
+		if first {
+			separator, first = "", false
+		}
+		return writeAll(w, separator, string(exportedRating))
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeAll(w, "]}")
+}
+
+// letterboxdRating rounds a score to the half stars Letterboxd uses
+func letterboxdRating(score float32) string {
+	return strconv.FormatFloat(math.Round(float64(score)*2)/2, 'f', -1, 64)
+}
+
+func writeAll(w io.Writer, parts ...string) error {
+	for _, part := range parts {
+		if _, err := io.WriteString(w, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportFixture() (*models.User, []*repository.RatedMovie) {
+	user := &models.User{
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		Name:      "Test User",
+		Taste:     pgvector.NewVector([]float32{0.6, 0.8}),
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	ratedAt := time.Date(2024, 2, 3, 10, 0, 0, 0, time.UTC)
+	ratedMovies := []*repository.RatedMovie{
+		{
+			Rating: &models.Rating{ID: uuid.New(), UserID: user.ID, MovieID: uuid.New(), Score: 4.3, CreatedAt: ratedAt, UpdatedAt: ratedAt},
+			Movie:  &models.Movie{Title: "The Matrix", Year: 1999},
+		},
+		{
+			Rating: &models.Rating{ID: uuid.New(), UserID: user.ID, MovieID: uuid.New(), Score: 1, CreatedAt: ratedAt, UpdatedAt: ratedAt},
+			Movie:  &models.Movie{Title: "Cats, the Movie", Year: 2019},
+		},
+	}
+	return user, ratedMovies
+}
+
+func TestExportService_WriteCSV(t *testing.T) {
+	ctx := context.Background()
+	user, ratedMovies := exportFixture()
+
+	tests := []struct {
+		name      string
+		mockSetup func(ratingRepo *MockRatingRepository)
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func(ratingRepo *MockRatingRepository) {
+				ratingRepo.On("ForEachByUser", ctx, user.ID).Return(ratedMovies, nil)
+			},
+			want: "Date,Name,Year,Letterboxd URI,Rating\n" +
+				"2024-02-03,The Matrix,1999,,4.5\n" +
+				"2024-02-03,\"Cats, the Movie\",2019,,1\n",
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func(ratingRepo *MockRatingRepository) {
+				ratingRepo.On("ForEachByUser", ctx, user.ID).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo := new(MockRatingRepository)
+			tt.mockSetup(mockRatingRepo)
+			service := NewExportService(mockRatingRepo)
+
+			var buf bytes.Buffer
+			err := service.WriteCSV(ctx, user, &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExportService.WriteCSV() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestExportService_WriteCSV_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	user, ratedMovies := exportFixture()
+	mockRatingRepo := new(MockRatingRepository)
+	mockRatingRepo.On("ForEachByUser", ctx, user.ID).Return(ratedMovies, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, NewExportService(mockRatingRepo).WriteCSV(ctx, user, &buf))
+
+	rows, unmatched, err := ParseRatingsCSV(&buf, "")
+	assert.NoError(t, err)
+	assert.Empty(t, unmatched)
+	assert.Equal(t, []ImportRow{
+		{Line: 2, Title: "The Matrix", Year: 1999, Score: 4.5},
+		{Line: 3, Title: "Cats, the Movie", Year: 2019, Score: 1},
+	}, rows)
+}
+
+func TestExportService_WriteJSON(t *testing.T) {
+	ctx := context.Background()
+	user, ratedMovies := exportFixture()
+
+	tests := []struct {
+		name        string
+		ratedMovies []*repository.RatedMovie
+		wantRatings int
+	}{
+		{name: "With Ratings", ratedMovies: ratedMovies, wantRatings: 2},
+		{name: "Without Ratings", ratedMovies: []*repository.RatedMovie{}, wantRatings: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo := new(MockRatingRepository)
+			mockRatingRepo.On("ForEachByUser", ctx, user.ID).Return(tt.ratedMovies, nil)
+			service := NewExportService(mockRatingRepo)
+
+			var buf bytes.Buffer
+			assert.NoError(t, service.WriteJSON(ctx, user, &buf))
+
+			var got struct {
+				User    ExportedUser     `json:"user"`
+				Taste   []float32        `json:"taste"`
+				Ratings []ExportedRating `json:"ratings"`
+			}
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+			assert.Equal(t, user.Email, got.User.Email)
+			assert.Equal(t, []float32{0.6, 0.8}, got.Taste)
+			assert.Len(t, got.Ratings, tt.wantRatings)
+			if tt.wantRatings > 0 {
+				assert.Equal(t, "The Matrix", got.Ratings[0].Title)
+				assert.Equal(t, float32(4.3), got.Ratings[0].Score)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnverifiedEmail  = apperr.Forbidden("the provider account has no verified email address")
+	ErrIdentityInUse    = apperr.Conflict("this provider account is already linked to another user")
+	ErrLastSignInMethod = apperr.Conflict("cannot unlink the only way to sign in, set a password or link another provider first")
+	ErrNoOfflineAccess  = apperr.NotFound("the provider account has not granted offline access")
+)
+
+// IdentityService signs users in with their provider accounts. An account can have several linked,
+// each matched on the provider's subject rather than on the email.
+type IdentityService struct {
+	identityRepo   repository.UserIdentityRepositoryInterface
+	userService    *UserService
+	accountService *AccountService
+	keyring        *auth.Keyring
+	providers      auth.ProviderRegistry
+}
+
+func NewIdentityService(identityRepo repository.UserIdentityRepositoryInterface, userService *UserService, accountService *AccountService) *IdentityService {
+	return &IdentityService{
+		identityRepo:   identityRepo,
+		userService:    userService,
+		accountService: accountService,
+	}
+}
+
+// SetRefreshTokens makes the service keep the refresh tokens providers grant, sealed with keyring,
+// so TokenSource can call the providers on the user's behalf. Without it refresh tokens are dropped.
+func (s *IdentityService) SetRefreshTokens(keyring *auth.Keyring, providers auth.ProviderRegistry) {
+	s.keyring, s.providers = keyring, providers
+}
+
+// SignIn returns the user a provider account belongs to, creating it on the first sign-in.
+// A provider account that isn't linked yet is linked to the user with its email, which the provider has to have verified.
+func (s *IdentityService) SignIn(ctx context.Context, identity *auth.Identity) (*models.User, error) {
+	linked, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	if linked != nil {
+		user, err = s.userService.GetUserByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %s of identity %s not found", linked.UserID, linked.ID)
+		}
+		if identity.Email != "" && identity.Email != linked.Email {
+			if err := s.identityRepo.UpdateEmail(ctx, linked.ID, identity.Email); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.updateRefreshToken(ctx, linked, identity); err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = s.userForNewIdentity(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.create(ctx, user, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if syncProfile(user, identity) {
+		if err := s.userService.UpdateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// userForNewIdentity finds the user with the identity's email or creates one
+func (s *IdentityService) userForNewIdentity(ctx context.Context, identity *auth.Identity) (*models.User, error) {
+	// Users are matched by email here, so it has to belong to whoever signed in
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrUnverifiedEmail
+	}
+
+	user, err := s.userService.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		verifiedAt := time.Now()
+		user = &models.User{
+			Email:           identity.Email,
+			Name:            identity.Name,
+			AvatarURL:       identity.Picture,
+			EmailVerifiedAt: &verifiedAt,
+		}
+		if err := s.userService.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if user.EmailVerifiedAt == nil {
+		// Someone signed up with this email and a password but never proved owning it, the provider just did
+		if err := s.accountService.ClaimUnverifiedAccount(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// Link adds a provider account to a signed in user, so they can sign in with either.
+// The provider account's email doesn't have to match the user's.
+func (s *IdentityService) Link(ctx context.Context, user *models.User, identity *auth.Identity) error {
+	linked, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return err
+	}
+	if linked != nil {
+		if linked.UserID != user.ID {
+			return ErrIdentityInUse
+		}
+		return s.updateRefreshToken(ctx, linked, identity)
+	}
+	return s.create(ctx, user, identity)
+}
+
+func (s *IdentityService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*models.UserIdentity, error) {
+	return s.identityRepo.ListByUser(ctx, userID)
+}
+
+// Unlink removes a provider account from the user, reporting false if the user has none with that ID.
+// The last one can only be removed once the user has a password to sign in with.
+func (s *IdentityService) Unlink(ctx context.Context, user *models.User, identityID uuid.UUID) (bool, error) {
+	identities, err := s.identityRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, identity := range identities {
+		if identity.ID == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	if len(identities) == 1 && user.PasswordHash == "" {
+		return false, ErrLastSignInMethod
+	}
+
+	return s.identityRepo.Delete(ctx, identityID, user.ID)
+}
+
+// TokenSource returns access tokens to call the provider with on the user's behalf, refreshed when they expire.
+// It fails with ErrNoOfflineAccess unless the user signed in with the provider and it granted a refresh token.
+func (s *IdentityService) TokenSource(ctx context.Context, userID uuid.UUID, providerName string) (oauth2.TokenSource, error) {
+	provider, ok := s.providers[providerName]
+	if !ok || s.keyring == nil {
+		return nil, ErrNoOfflineAccess
+	}
+	identities, err := s.identityRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider != providerName || identity.RefreshToken == "" {
+			continue
+		}
+		refreshToken, err := s.keyring.Decrypt(identity.RefreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open refresh token of identity %s: %w", identity.ID, err)
+		}
+		return &storingTokenSource{
+			ctx:          ctx,
+			service:      s,
+			identityID:   identity.ID,
+			refreshToken: refreshToken,
+			base:         provider.TokenSource(ctx, refreshToken),
+		}, nil
+	}
+	return nil, ErrNoOfflineAccess
+}
+
+func (s *IdentityService) create(ctx context.Context, user *models.User, identity *auth.Identity) error {
+	refreshToken, err := s.sealRefreshToken(identity)
+	if err != nil {
+		return err
+	}
+	return s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:       user.ID,
+		Provider:     identity.Provider,
+		Subject:      identity.Subject,
+		Email:        identity.Email,
+		RefreshToken: refreshToken,
+	})
+}
+
+// sealRefreshToken returns the identity's refresh token ready to store, or "" if there is none to keep
+func (s *IdentityService) sealRefreshToken(identity *auth.Identity) (string, error) {
+	if identity.RefreshToken == "" || s.keyring == nil {
+		return "", nil
+	}
+	return s.keyring.Encrypt(identity.RefreshToken)
+}
+
+// updateRefreshToken stores a newly granted refresh token. Providers don't grant one on every sign-in,
+// so the earlier one is kept when none came.
+func (s *IdentityService) updateRefreshToken(ctx context.Context, linked *models.UserIdentity, identity *auth.Identity) error {
+	refreshToken, err := s.sealRefreshToken(identity)
+	if err != nil || refreshToken == "" {
+		return err
+	}
+	return s.identityRepo.UpdateRefreshToken(ctx, linked.ID, refreshToken)
+}
+
+// storingTokenSource stores the refresh token again when the provider rotates it while refreshing
+type storingTokenSource struct {
+	ctx          context.Context
+	service      *IdentityService
+	identityID   uuid.UUID
+	base         oauth2.TokenSource
+	mu           sync.Mutex
+	refreshToken string
+}
+
+func (t *storingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := t.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if token.RefreshToken == "" || token.RefreshToken == t.refreshToken {
+		return token, nil
+	}
+	sealed, err := t.service.keyring.Encrypt(token.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.service.identityRepo.UpdateRefreshToken(t.ctx, t.identityID, sealed); err != nil {
+		return nil, err
+	}
+	t.refreshToken = token.RefreshToken
+	return token, nil
+}
+
+// syncProfile copies the name and picture the provider knows about onto user, reporting whether anything changed.
+// Empty values are skipped so signing in with a provider that shares less doesn't wipe the profile.
+func syncProfile(user *models.User, identity *auth.Identity) bool {
+	changed := false
+	if identity.Name != "" && identity.Name != user.Name {
+		user.Name, changed = identity.Name, true
+	}
+	if identity.Picture != "" && identity.Picture != user.AvatarURL {
+		user.AvatarURL, changed = identity.Picture, true
+	}
+	return changed
+}
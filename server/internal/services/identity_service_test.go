@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type MockUserIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	args := m.Called(ctx, provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserIdentity), args.Error(1)
+}
+
+func (m *MockUserIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.UserIdentity), args.Error(1)
+}
+
+func (m *MockUserIdentityRepository) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) UpdateRefreshToken(ctx context.Context, id uuid.UUID, refreshToken string) error {
+	args := m.Called(ctx, id, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) Delete(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func newTestIdentityService() (*IdentityService, *MockUserIdentityRepository, *MockUserRepository) {
+	identityRepo := new(MockUserIdentityRepository)
+	userRepo := new(MockUserRepository)
+	accountService := NewAccountService(userRepo, new(MockAccountTokenRepository), &recordingMailer{}, "https://nextwatch.example")
+	return NewIdentityService(identityRepo, NewUserService(userRepo, new(MockRatingRepository)), accountService), identityRepo, userRepo
+}
+
+func TestIdentityService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	verifiedAt := time.Now().Add(-time.Hour)
+	existingUser := func() *models.User {
+		return &models.User{ID: uuid.New(), Email: "ada@example.com", Name: "Ada", EmailVerifiedAt: &verifiedAt}
+	}
+
+	tests := []struct {
+		name      string
+		identity  *auth.Identity
+		mockSetup func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User)
+		wantErr   error
+		check     func(t *testing.T, got, user *models.User)
+	}{
+		{
+			name:     "Success - Linked Identity With Changed Email",
+			identity: &auth.Identity{Provider: "google", Subject: "subject", Email: "ada@new.example", EmailVerified: true, Name: "Ada"},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				linked := &models.UserIdentity{ID: uuid.New(), UserID: user.ID, Provider: "google", Subject: "subject", Email: "ada@example.com"}
+				identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(linked, nil)
+				userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+				identityRepo.On("UpdateEmail", ctx, linked.ID, "ada@new.example").Return(nil)
+			},
+			check: func(t *testing.T, got, user *models.User) {
+				assert.Equal(t, user.ID, got.ID)
+				assert.Equal(t, "ada@example.com", got.Email)
+			},
+		},
+		{
+			name:     "Success - Linked Identity Without Verified Email",
+			identity: &auth.Identity{Provider: "google", Subject: "subject"},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				linked := &models.UserIdentity{ID: uuid.New(), UserID: user.ID, Provider: "google", Subject: "subject", Email: "ada@example.com"}
+				identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(linked, nil)
+				userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+			},
+			check: func(t *testing.T, got, user *models.User) {
+				assert.Equal(t, user.ID, got.ID)
+			},
+		},
+		{
+			name:     "Success - Links Existing User By Email",
+			identity: &auth.Identity{Provider: "keycloak", Subject: "subject", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				identityRepo.On("GetByProviderSubject", ctx, "keycloak", "subject").Return(nil, nil)
+				userRepo.On("GetByEmail", ctx, "ada@example.com").Return(user, nil)
+				identityRepo.On("Create", ctx, mock.MatchedBy(func(identity *models.UserIdentity) bool {
+					return identity.UserID == user.ID && identity.Provider == "keycloak" && identity.Subject == "subject"
+				})).Return(nil)
+				userRepo.On("Update", ctx, user).Return(nil)
+			},
+			check: func(t *testing.T, got, user *models.User) {
+				assert.Equal(t, user.ID, got.ID)
+				assert.Equal(t, "Ada Lovelace", got.Name)
+			},
+		},
+		{
+			name:     "Success - Creates User",
+			identity: &auth.Identity{Provider: "google", Subject: "subject", Email: "new@example.com", EmailVerified: true},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(nil, nil)
+				userRepo.On("GetByEmail", ctx, "new@example.com").Return(nil, nil)
+				userRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+				identityRepo.On("Create", ctx, mock.AnythingOfType("*models.UserIdentity")).Return(nil)
+			},
+			check: func(t *testing.T, got, user *models.User) {
+				assert.NotEqual(t, user.ID, got.ID)
+				assert.Equal(t, "new@example.com", got.Email)
+				assert.NotNil(t, got.EmailVerifiedAt)
+			},
+		},
+		{
+			name:     "Success - Claims Unverified Account",
+			identity: &auth.Identity{Provider: "google", Subject: "subject", Email: "ada@example.com", EmailVerified: true},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				user.EmailVerifiedAt = nil
+				user.PasswordHash = "set by someone else"
+				identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(nil, nil)
+				userRepo.On("GetByEmail", ctx, "ada@example.com").Return(user, nil)
+				userRepo.On("UpdatePassword", ctx, user.ID, "").Return(nil)
+				userRepo.On("MarkEmailVerified", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+				identityRepo.On("Create", ctx, mock.AnythingOfType("*models.UserIdentity")).Return(nil)
+			},
+			check: func(t *testing.T, got, user *models.User) {
+				assert.Empty(t, got.PasswordHash)
+				assert.NotNil(t, got.EmailVerifiedAt)
+			},
+		},
+		{
+			name:     "Error - New Identity With Unverified Email",
+			identity: &auth.Identity{Provider: "google", Subject: "subject", Email: "ada@example.com", EmailVerified: false},
+			mockSetup: func(identityRepo *MockUserIdentityRepository, userRepo *MockUserRepository, user *models.User) {
+				identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(nil, nil)
+			},
+			wantErr: ErrUnverifiedEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, identityRepo, userRepo := newTestIdentityService()
+			user := existingUser()
+			tt.mockSetup(identityRepo, userRepo, user)
+
+			got, err := service.SignIn(ctx, tt.identity)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.check(t, got, user)
+			identityRepo.AssertExpectations(t)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdentityService_Link(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New()}
+	identity := &auth.Identity{Provider: "keycloak", Subject: "subject", Email: "ada@work.example"}
+
+	t.Run("Success", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		identityRepo.On("GetByProviderSubject", ctx, "keycloak", "subject").Return(nil, nil)
+		identityRepo.On("Create", ctx, &models.UserIdentity{UserID: user.ID, Provider: "keycloak", Subject: "subject", Email: "ada@work.example"}).Return(nil)
+
+		assert.NoError(t, service.Link(ctx, user, identity))
+		identityRepo.AssertExpectations(t)
+	})
+
+	t.Run("Already Linked", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		identityRepo.On("GetByProviderSubject", ctx, "keycloak", "subject").Return(&models.UserIdentity{UserID: user.ID}, nil)
+
+		assert.NoError(t, service.Link(ctx, user, identity))
+		identityRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - Linked To Another User", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		identityRepo.On("GetByProviderSubject", ctx, "keycloak", "subject").Return(&models.UserIdentity{UserID: uuid.New()}, nil)
+
+		assert.ErrorIs(t, service.Link(ctx, user, identity), ErrIdentityInUse)
+	})
+}
+
+// rotatingProvider answers every refresh with a new refresh token, like providers with rotation do
+type rotatingProvider struct {
+	auth.IdentityProvider
+	refreshedWith []string
+}
+
+func (p *rotatingProvider) TokenSource(ctx context.Context, refreshToken string) oauth2.TokenSource {
+	p.refreshedWith = append(p.refreshedWith, refreshToken)
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access", RefreshToken: "rotated", Expiry: time.Now().Add(time.Hour)})
+}
+
+func TestIdentityService_RefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := auth.NewKeyring("v1", map[string][]byte{"v1": []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	user := &models.User{ID: uuid.New(), Email: "ada@example.com"}
+	linked := &models.UserIdentity{ID: uuid.New(), UserID: user.ID, Provider: "google", Subject: "subject", Email: "ada@example.com"}
+	opensTo := func(plaintext string) interface{} {
+		return mock.MatchedBy(func(sealed string) bool {
+			opened, err := keyring.Decrypt(sealed)
+			return err == nil && opened == plaintext
+		})
+	}
+
+	t.Run("Sign In Keeps Granted Token Sealed", func(t *testing.T) {
+		service, identityRepo, userRepo := newTestIdentityService()
+		service.SetRefreshTokens(keyring, auth.ProviderRegistry{})
+		identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(linked, nil)
+		userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		identityRepo.On("UpdateRefreshToken", ctx, linked.ID, opensTo("granted")).Return(nil)
+
+		_, err := service.SignIn(ctx, &auth.Identity{Provider: "google", Subject: "subject", RefreshToken: "granted"})
+		require.NoError(t, err)
+		identityRepo.AssertExpectations(t)
+	})
+
+	t.Run("Dropped Without Keyring", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		identityRepo.On("GetByProviderSubject", ctx, "google", "subject").Return(nil, nil)
+		identityRepo.On("Create", ctx, &models.UserIdentity{UserID: user.ID, Provider: "google", Subject: "subject"}).Return(nil)
+
+		require.NoError(t, service.Link(ctx, user, &auth.Identity{Provider: "google", Subject: "subject", RefreshToken: "granted"}))
+		identityRepo.AssertExpectations(t)
+	})
+
+	t.Run("Token Source Stores Rotated Token", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		provider := &rotatingProvider{}
+		service.SetRefreshTokens(keyring, auth.ProviderRegistry{"google": provider})
+		sealed, err := keyring.Encrypt("granted")
+		require.NoError(t, err)
+		stored := *linked
+		stored.RefreshToken = sealed
+		identityRepo.On("ListByUser", ctx, user.ID).Return([]*models.UserIdentity{&stored}, nil)
+		identityRepo.On("UpdateRefreshToken", ctx, linked.ID, opensTo("rotated")).Return(nil).Once()
+
+		source, err := service.TokenSource(ctx, user.ID, "google")
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			token, err := source.Token()
+			require.NoError(t, err)
+			assert.Equal(t, "access", token.AccessToken)
+		}
+		assert.Equal(t, []string{"granted"}, provider.refreshedWith)
+		identityRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - No Offline Access", func(t *testing.T) {
+		service, identityRepo, _ := newTestIdentityService()
+		service.SetRefreshTokens(keyring, auth.ProviderRegistry{"google": &rotatingProvider{}})
+		identityRepo.On("ListByUser", ctx, user.ID).Return([]*models.UserIdentity{linked}, nil)
+
+		_, err := service.TokenSource(ctx, user.ID, "google")
+		assert.ErrorIs(t, err, ErrNoOfflineAccess)
+		_, err = service.TokenSource(ctx, user.ID, "keycloak")
+		assert.ErrorIs(t, err, ErrNoOfflineAccess)
+	})
+}
+
+func TestIdentityService_Unlink(t *testing.T) {
+	ctx := context.Background()
+	google := &models.UserIdentity{ID: uuid.New(), Provider: "google"}
+	keycloak := &models.UserIdentity{ID: uuid.New(), Provider: "keycloak"}
+
+	tests := []struct {
+		name         string
+		user         *models.User
+		identities   []*models.UserIdentity
+		identityID   uuid.UUID
+		expectDelete bool
+		want         bool
+		wantErr      error
+	}{
+		{
+			name:         "Success",
+			user:         &models.User{ID: uuid.New()},
+			identities:   []*models.UserIdentity{google, keycloak},
+			identityID:   keycloak.ID,
+			expectDelete: true,
+			want:         true,
+		},
+		{
+			name:         "Success - Last Identity With Password",
+			user:         &models.User{ID: uuid.New(), PasswordHash: "hash"},
+			identities:   []*models.UserIdentity{google},
+			identityID:   google.ID,
+			expectDelete: true,
+			want:         true,
+		},
+		{
+			name:       "Not Found",
+			user:       &models.User{ID: uuid.New()},
+			identities: []*models.UserIdentity{google},
+			identityID: uuid.New(),
+			want:       false,
+		},
+		{
+			name:       "Error - Last Sign In Method",
+			user:       &models.User{ID: uuid.New()},
+			identities: []*models.UserIdentity{google},
+			identityID: google.ID,
+			wantErr:    ErrLastSignInMethod,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, identityRepo, _ := newTestIdentityService()
+			identityRepo.On("ListByUser", ctx, tt.user.ID).Return(tt.identities, nil)
+			if tt.expectDelete {
+				identityRepo.On("Delete", ctx, tt.identityID, tt.user.ID).Return(true, nil)
+			}
+
+			got, err := service.Unlink(ctx, tt.user, tt.identityID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			identityRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/agnivade/levenshtein"
+	"github.com/google/uuid"
+)
+
+type RatingImportFormat string
+
+const (
+	LetterboxdFormat RatingImportFormat = "letterboxd"
+	IMDbFormat       RatingImportFormat = "imdb"
+)
+
+// Minimum title similarity for a fuzzy match to be accepted
+const fuzzyMatchThreshold = 0.85
+
+// ImportRow is a single rating read from an exported ratings file, already rescaled to 0-5
+type ImportRow struct {
+	Line  int
+	Title string
+	Year  int
+	Score float32
+}
+
+// UnmatchedRow is a row of an imported file that did not end up as a rating
+type UnmatchedRow struct {
+	Line   int
+	Title  string
+	Year   int
+	Reason string
+}
+
+type ImportResult struct {
+	Imported  int
+	Unmatched []UnmatchedRow
+}
+
+type ImportService struct {
+	ratingRepo     repository.RatingRepositoryInterface
+	movieRepo      repository.MovieRepositoryInterface
+	tasteWeighting TasteWeighting
+	live           *LiveService
+}
+
+func NewImportService(ratingRepo repository.RatingRepositoryInterface, movieRepo repository.MovieRepositoryInterface) *ImportService {
+	return &ImportService{
+		ratingRepo: ratingRepo,
+		movieRepo:  movieRepo,
+	}
+}
+
+// SetTasteWeighting switches how imported scores are turned into taste vector weights
+func (s *ImportService) SetTasteWeighting(weighting TasteWeighting) {
+	s.tasteWeighting = weighting
+}
+
+// SetLiveService announces imported ratings to subscriptions
+func (s *ImportService) SetLiveService(live *LiveService) {
+	s.live = live
+}
+
+// ImportRatings reads a Letterboxd or IMDb ratings export and stores a rating for every row that matches
+// a movie. The ratings, the user's statistics and their taste are saved together in one transaction.
+// An empty format is detected from the file header.
+func (s *ImportService) ImportRatings(ctx context.Context, user *models.User, r io.Reader, format RatingImportFormat) (*ImportResult, error) {
+	rows, unmatched, err := ParseRatingsCSV(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		row   ImportRow
+		movie *models.Movie
+	}
+
+	movies, err := matchMovies(ctx, s.movieRepo, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later rows win when the same movie appears more than once
+	matches := make(map[uuid.UUID]match)
+	var order []uuid.UUID
+	for i, row := range rows {
+		movie := movies[i]
+		if movie == nil {
+			unmatched = append(unmatched, UnmatchedRow{Line: row.Line, Title: row.Title, Year: row.Year, Reason: "no matching movie"})
+			continue
+		}
+		if _, seen := matches[movie.ID]; !seen {
+			order = append(order, movie.ID)
+		}
+		matches[movie.ID] = match{row: row, movie: movie}
+	}
+
+	result := &ImportResult{}
+	if len(order) > 0 {
+		existingRatings, err := s.ratingRepo.GetByUserAndMovies(ctx, user.ID, order)
+		if err != nil {
+			return nil, err
+		}
+		scored := make([]scoredMovie, len(order))
+		for i, movieID := range order {
+			scored[i] = scoredMovie{movie: matches[movieID].movie, score: matches[movieID].row.Score}
+		}
+		if _, _, err := saveRatings(ctx, s.ratingRepo, s.tasteWeighting, user, existingRatings, scored); err != nil {
+			return nil, err
+		}
+		result.Imported = len(order)
+		s.live.RatingsChanged(ctx, user.ID, order...)
+	}
+
+	sort.Slice(unmatched, func(i, j int) bool { return unmatched[i].Line < unmatched[j].Line })
+	result.Unmatched = unmatched
+	return result, nil
+}
+
+// ParseRatingsCSV parses a Letterboxd or IMDb ratings.csv export.
+// Rows that cannot be used are returned as unmatched instead of failing the whole file.
+func ParseRatingsCSV(r io.Reader, format RatingImportFormat) ([]ImportRow, []UnmatchedRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, apperr.Validation("ratings file is empty")
+	}
+	if err != nil {
+		return nil, nil, apperr.Validation(fmt.Sprintf("failed to read ratings file: %v", err))
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if format == "" {
+		format = detectRatingImportFormat(columns)
+	}
+
+	var titleColumn, ratingColumn string
+	var scale float64
+	switch format {
+	case LetterboxdFormat:
+		titleColumn, ratingColumn, scale = "name", "rating", 1 // 0.5-5 stars
+	case IMDbFormat:
+		titleColumn, ratingColumn, scale = "title", "your rating", 0.5 // 1-10
+	default:
+		return nil, nil, apperr.Validation("unrecognized ratings file format")
+	}
+
+	titleIdx, hasTitle := columns[titleColumn]
+	yearIdx, hasYear := columns["year"]
+	ratingIdx, hasRating := columns[ratingColumn]
+	if !hasTitle || !hasYear || !hasRating {
+		return nil, nil, apperr.Validation(fmt.Sprintf("ratings file is missing one of the %q, %q or %q columns", titleColumn, "year", ratingColumn))
+	}
+
+	var rows []ImportRow
+	var unmatched []UnmatchedRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, apperr.Validation(fmt.Sprintf("failed to read ratings file: %v", err))
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(idx int) string {
+			if idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		title := field(titleIdx)
+		year, yearErr := strconv.Atoi(field(yearIdx))
+		rating, ratingErr := strconv.ParseFloat(field(ratingIdx), 64)
+		score := rating * scale
+
+		switch {
+		case title == "":
+			unmatched = append(unmatched, UnmatchedRow{Line: line, Year: year, Reason: "missing title"})
+		case yearErr != nil:
+			unmatched = append(unmatched, UnmatchedRow{Line: line, Title: title, Reason: "invalid year"})
+		case ratingErr != nil || score < 0 || score > 5:
+			unmatched = append(unmatched, UnmatchedRow{Line: line, Title: title, Year: year, Reason: "invalid rating"})
+		default:
+			rows = append(rows, ImportRow{Line: line, Title: title, Year: year, Score: float32(score)})
+		}
+	}
+
+	return rows, unmatched, nil
+}
+
+func detectRatingImportFormat(columns map[string]int) RatingImportFormat {
+	if _, ok := columns["your rating"]; ok {
+		return IMDbFormat
+	}
+	if _, ok := columns["letterboxd uri"]; ok {
+		return LetterboxdFormat
+	}
+	return ""
+}
+
+// matchMovies resolves imported titles to movies, returning nil for rows without a match. Exact matches
+// are looked up in one query, the rest are matched fuzzily against the movies of the surrounding years.
+func matchMovies(ctx context.Context, movieRepo repository.MovieRepositoryInterface, rows []ImportRow) ([]*models.Movie, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[repository.TitleYear]bool, len(rows))
+	keys := make([]repository.TitleYear, 0, len(rows))
+	for _, row := range rows {
+		key := repository.TitleYear{Title: strings.ToLower(row.Title), Year: row.Year}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	found, err := movieRepo.GetByTitlesAndYears(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	exact := make(map[repository.TitleYear]*models.Movie, len(found))
+	for _, movie := range found {
+		exact[repository.TitleYear{Title: strings.ToLower(movie.Title), Year: movie.Year}] = movie
+	}
+
+	// Release years may differ by one between databases
+	matches := make([]*models.Movie, len(rows))
+	fuzzyYears := make(map[int]bool)
+	for i, row := range rows {
+		if movie, ok := exact[repository.TitleYear{Title: strings.ToLower(row.Title), Year: row.Year}]; ok {
+			matches[i] = movie
+			continue
+		}
+		for year := row.Year - 1; year <= row.Year+1; year++ {
+			fuzzyYears[year] = true
+		}
+	}
+	if len(fuzzyYears) == 0 {
+		return matches, nil
+	}
+
+	candidates, err := moviesByYear(ctx, movieRepo, fuzzyYears)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		if matches[i] == nil {
+			matches[i] = fuzzyMatch(row, candidates)
+		}
+	}
+	return matches, nil
+}
+
+// moviesByYear fetches the movies of the given years with one query per run of consecutive years
+func moviesByYear(ctx context.Context, movieRepo repository.MovieRepositoryInterface, years map[int]bool) (map[int][]*models.Movie, error) {
+	sorted := make([]int, 0, len(years))
+	for year := range years {
+		sorted = append(sorted, year)
+	}
+	sort.Ints(sorted)
+
+	byYear := make(map[int][]*models.Movie)
+	for start := 0; start < len(sorted); {
+		end := start
+		for end+1 < len(sorted) && sorted[end+1] == sorted[end]+1 {
+			end++
+		}
+		movies, err := movieRepo.GetByYearRange(ctx, sorted[start], sorted[end])
+		if err != nil {
+			return nil, err
+		}
+		for _, movie := range movies {
+			byYear[movie.Year] = append(byYear[movie.Year], movie)
+		}
+		start = end + 1
+	}
+	return byYear, nil
+}
+
+// fuzzyMatch returns the movie with the closest title from the row's year or the years around it
+func fuzzyMatch(row ImportRow, candidates map[int][]*models.Movie) *models.Movie {
+	normalized := normalizeTitle(row.Title)
+	var best *models.Movie
+	bestSimilarity := 0.0
+	for year := row.Year - 1; year <= row.Year+1; year++ {
+		for _, candidate := range candidates[year] {
+			similarity := titleSimilarity(normalized, normalizeTitle(candidate.Title))
+			if year != row.Year {
+				similarity -= 0.05
+			}
+			if similarity > bestSimilarity {
+				best, bestSimilarity = candidate, similarity
+			}
+		}
+	}
+
+	if bestSimilarity < fuzzyMatchThreshold {
+		return nil
+	}
+	return best
+}
+
+// normalizeTitle lowercases a title and drops punctuation and leading articles
+func normalizeTitle(title string) string {
+	title = strings.ReplaceAll(strings.ToLower(title), "&", " and ")
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+
+	for _, article := range []string{"the ", "a ", "an "} {
+		if strings.HasPrefix(title, article) {
+			return strings.TrimPrefix(title, article)
+		}
+	}
+	return title
+}
+
+// titleSimilarity returns 1 for identical titles and approaches 0 as they differ
+func titleSimilarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein.ComputeDistance(a, b))/float64(longest)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const letterboxdRatingsCSV = `Date,Name,Year,Letterboxd URI,Rating
+2023-01-02,The Matrix,1999,https://boxd.it/1,4.5
+2023-01-03,Spirited Away,2001,https://boxd.it/2,5
+2023-01-04,Unknown Film,2010,https://boxd.it/3,2
+2023-01-05,Broken Row,not-a-year,https://boxd.it/4,3
+`
+
+const imdbRatingsCSV = `Const,Your Rating,Date Rated,Title,URL,Title Type,IMDb Rating,Runtime (mins),Year,Genres,Num Votes,Release Date,Directors
+tt0133093,8,2023-01-02,The Matrix,https://www.imdb.com/title/tt0133093/,movie,8.7,136,1999,"Action, Sci-Fi",1900000,1999-03-24,Lana Wachowski
+tt0245429,11,2023-01-03,Spirited Away,https://www.imdb.com/title/tt0245429/,movie,8.6,125,2001,Animation,800000,2001-07-20,Hayao Miyazaki
+`
+
+func TestParseRatingsCSV(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		format        RatingImportFormat
+		wantRows      []ImportRow
+		wantUnmatched []UnmatchedRow
+		wantErr       bool
+	}{
+		{
+			name:   "Letterboxd - Detected",
+			input:  letterboxdRatingsCSV,
+			format: "",
+			wantRows: []ImportRow{
+				{Line: 2, Title: "The Matrix", Year: 1999, Score: 4.5},
+				{Line: 3, Title: "Spirited Away", Year: 2001, Score: 5},
+				{Line: 4, Title: "Unknown Film", Year: 2010, Score: 2},
+			},
+			wantUnmatched: []UnmatchedRow{
+				{Line: 5, Title: "Broken Row", Reason: "invalid year"},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "IMDb - Rescaled",
+			input:  imdbRatingsCSV,
+			format: IMDbFormat,
+			wantRows: []ImportRow{
+				{Line: 2, Title: "The Matrix", Year: 1999, Score: 4},
+			},
+			wantUnmatched: []UnmatchedRow{
+				{Line: 3, Title: "Spirited Away", Year: 2001, Reason: "invalid rating"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Error - Unknown Format",
+			input:   "foo,bar\n1,2\n",
+			format:  "",
+			wantErr: true,
+		},
+		{
+			name:    "Error - Missing Columns",
+			input:   "Date,Name,Letterboxd URI\n2023-01-02,The Matrix,https://boxd.it/1\n",
+			format:  LetterboxdFormat,
+			wantErr: true,
+		},
+		{
+			name:    "Error - Empty File",
+			input:   "",
+			format:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, unmatched, err := ParseRatingsCSV(strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRatingsCSV() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantRows, rows)
+			assert.Equal(t, tt.wantUnmatched, unmatched)
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	assert.Equal(t, "lord of the rings the fellowship of the ring", normalizeTitle("The Lord of the Rings: The Fellowship of the Ring"))
+	assert.Equal(t, "fast and furious", normalizeTitle("Fast & Furious"))
+	assert.Equal(t, "amélie", normalizeTitle("Amélie"))
+}
+
+func TestImportService_ImportRatings(t *testing.T) {
+	ctx := context.Background()
+	matrix := &models.Movie{ID: uuid.New(), Title: "The Matrix", Year: 1999, Embedding: pgvector.NewVector([]float32{1, 0})}
+	spiritedAway := &models.Movie{ID: uuid.New(), Title: "Spirited Away", Year: 2001, Embedding: pgvector.NewVector([]float32{0, 1})}
+	csvKeys := []repository.TitleYear{{Title: "the matrix", Year: 1999}, {Title: "spirited away", Year: 2001}, {Title: "unknown film", Year: 2010}}
+
+	tests := []struct {
+		name          string
+		mockSetup     func(ratingRepo *MockRatingRepository, movieRepo *MockMovieRepository)
+		wantImported  int
+		wantUnmatched []UnmatchedRow
+		wantErr       bool
+	}{
+		{
+			name: "Success - Exact And Fuzzy Matches",
+			mockSetup: func(ratingRepo *MockRatingRepository, movieRepo *MockMovieRepository) {
+				movieRepo.On("GetByTitlesAndYears", ctx, csvKeys).Return([]*models.Movie{matrix}, nil).Once()
+				// One query per run of years around the rows without an exact match
+				movieRepo.On("GetByYearRange", ctx, 2000, 2002).Return([]*models.Movie{{ID: spiritedAway.ID, Title: "Spirited Away!", Year: 2001, Embedding: spiritedAway.Embedding}}, nil).Once()
+				movieRepo.On("GetByYearRange", ctx, 2009, 2011).Return([]*models.Movie{}, nil).Once()
+				ratingRepo.On("GetByUserAndMovies", ctx, mock.AnythingOfType("uuid.UUID"), []uuid.UUID{matrix.ID, spiritedAway.ID}).
+					Return(map[uuid.UUID]*models.Rating{matrix.ID: {ID: uuid.New(), MovieID: matrix.ID, Score: 1}}, nil).Once()
+				// The re-rated Matrix keeps its rating ID, Spirited Away gets a new rating, both with the user's new statistics
+				ratingRepo.On("SaveBatch", ctx, mock.MatchedBy(func(user *models.User) bool {
+					return user.ScoreStats.Count == 2
+				}), mock.MatchedBy(func(ratings []*models.Rating) bool {
+					return len(ratings) == 2 && ratings[0].ID != uuid.Nil && ratings[0].Score == 4.5 && ratings[1].ID == uuid.Nil
+				})).Return(nil).Once()
+			},
+			wantImported: 2,
+			wantUnmatched: []UnmatchedRow{
+				{Line: 4, Title: "Unknown Film", Year: 2010, Reason: "no matching movie"},
+				{Line: 5, Title: "Broken Row", Reason: "invalid year"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error - Nothing Saved When The Batch Fails",
+			mockSetup: func(ratingRepo *MockRatingRepository, movieRepo *MockMovieRepository) {
+				movieRepo.On("GetByTitlesAndYears", ctx, csvKeys).Return([]*models.Movie{matrix, spiritedAway}, nil)
+				movieRepo.On("GetByYearRange", ctx, 2009, 2011).Return([]*models.Movie{}, nil)
+				ratingRepo.On("GetByUserAndMovies", ctx, mock.AnythingOfType("uuid.UUID"), []uuid.UUID{matrix.ID, spiritedAway.ID}).Return(map[uuid.UUID]*models.Rating{}, nil)
+				ratingRepo.On("SaveBatch", ctx, mock.AnythingOfType("*models.User"), mock.AnythingOfType("[]*models.Rating")).Return(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error - Repository Failure",
+			mockSetup: func(ratingRepo *MockRatingRepository, movieRepo *MockMovieRepository) {
+				movieRepo.On("GetByTitlesAndYears", ctx, csvKeys).Return(nil, errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo := new(MockRatingRepository)
+			mockMovieRepo := new(MockMovieRepository)
+			service := NewImportService(mockRatingRepo, mockMovieRepo)
+			tt.mockSetup(mockRatingRepo, mockMovieRepo)
+
+			user := &models.User{ID: uuid.New(), Taste: pgvector.NewVector(make([]float32, 2))}
+			got, err := service.ImportRatings(ctx, user, strings.NewReader(letterboxdRatingsCSV), "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ImportService.ImportRatings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				// The user's statistics only change once the ratings are saved
+				assert.Equal(t, models.ScoreStats{}, user.ScoreStats)
+				return
+			}
+			assert.Equal(t, tt.wantImported, got.Imported)
+			assert.Equal(t, tt.wantUnmatched, got.Unmatched)
+			assert.InDelta(t, 1, user.Taste.Slice()[0]*user.Taste.Slice()[0]+user.Taste.Slice()[1]*user.Taste.Slice()[1], 1e-6)
+			mockRatingRepo.AssertExpectations(t)
+			mockMovieRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/Azanul/Next-Watch/internal/pubsub"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+// LiveService tells subscriptions when ratings change. Events only name what changed,
+// subscribers read the new data themselves.
+type LiveService struct {
+	pubsub     pubsub.PubSub
+	ratingRepo repository.RatingRepositoryInterface
+}
+
+func NewLiveService(ps pubsub.PubSub, ratingRepo repository.RatingRepositoryInterface) *LiveService {
+	return &LiveService{
+		pubsub:     ps,
+		ratingRepo: ratingRepo,
+	}
+}
+
+func recommendationsTopic(userID uuid.UUID) string {
+	return "recommendations:" + userID.String()
+}
+
+func movieRatingsTopic(movieID uuid.UUID) string {
+	return "movie_ratings:" + movieID.String()
+}
+
+// RatingsChanged announces that a user rated, re-rated or removed ratings of movies. The ratings are
+// already saved when it is called, so failures are only logged. A nil LiveService announces nothing.
+func (s *LiveService) RatingsChanged(ctx context.Context, userID uuid.UUID, movieIDs ...uuid.UUID) {
+	if s == nil {
+		return
+	}
+	if err := s.pubsub.Publish(ctx, recommendationsTopic(userID), ""); err != nil {
+		log.Printf("Failed to publish recommendations update: %v", err)
+	}
+	for _, movieID := range movieIDs {
+		if err := s.pubsub.Publish(ctx, movieRatingsTopic(movieID), ""); err != nil {
+			log.Printf("Failed to publish movie rating update: %v", err)
+		}
+	}
+}
+
+// SubscribeRecommendations returns a channel that receives whenever the user's ratings, and so their
+// recommendations, changed. It is closed when ctx is done.
+func (s *LiveService) SubscribeRecommendations(ctx context.Context, userID uuid.UUID) (<-chan struct{}, error) {
+	events, err := s.pubsub.Subscribe(ctx, recommendationsTopic(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{})
+	go func() {
+		defer close(changes)
+		for range events {
+			select {
+			case changes <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// SubscribeMovieRatingSummary returns a channel that receives the summary of a movie's ratings
+// whenever someone rates it. It is closed when ctx is done or reading the summary fails.
+func (s *LiveService) SubscribeMovieRatingSummary(ctx context.Context, movieID uuid.UUID) (<-chan *repository.RatingSummary, error) {
+	events, err := s.pubsub.Subscribe(ctx, movieRatingsTopic(movieID))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(chan *repository.RatingSummary)
+	go func() {
+		defer close(summaries)
+		for range events {
+			summary, err := s.ratingRepo.GetSummaryByMovie(ctx, movieID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to get movie rating summary: %v", err)
+				}
+				return
+			}
+			select {
+			case summaries <- summary:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return summaries, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/pubsub"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveService(t *testing.T) {
+	userID, movieID := uuid.New(), uuid.New()
+
+	t.Run("Rating changes reach the user's recommendations and the movie's summary", func(t *testing.T) {
+		mockRatingRepo := new(MockRatingRepository)
+		service := NewLiveService(pubsub.NewMemory(), mockRatingRepo)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockRatingRepo.On("GetSummaryByMovie", mock.Anything, movieID).Return(&repository.RatingSummary{Count: 2, AverageScore: 4}, nil)
+
+		recommendations, err := service.SubscribeRecommendations(ctx, userID)
+		require.NoError(t, err)
+		summaries, err := service.SubscribeMovieRatingSummary(ctx, movieID)
+		require.NoError(t, err)
+		otherUser, err := service.SubscribeRecommendations(ctx, uuid.New())
+		require.NoError(t, err)
+
+		service.RatingsChanged(ctx, userID, movieID)
+
+		select {
+		case <-recommendations:
+		case <-time.After(time.Second):
+			t.Fatal("recommendations were not updated")
+		}
+		select {
+		case summary := <-summaries:
+			assert.Equal(t, &repository.RatingSummary{Count: 2, AverageScore: 4}, summary)
+		case <-time.After(time.Second):
+			t.Fatal("movie rating summary was not updated")
+		}
+		select {
+		case <-otherUser:
+			t.Fatal("another user's recommendations were updated")
+		case <-time.After(10 * time.Millisecond):
+		}
+		mockRatingRepo.AssertExpectations(t)
+	})
+
+	t.Run("Channels close when the subscriber is done", func(t *testing.T) {
+		service := NewLiveService(pubsub.NewMemory(), new(MockRatingRepository))
+		ctx, cancel := context.WithCancel(context.Background())
+
+		recommendations, err := service.SubscribeRecommendations(ctx, userID)
+		require.NoError(t, err)
+		cancel()
+
+		select {
+		case _, ok := <-recommendations:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel was not closed")
+		}
+	})
+
+	t.Run("Nil service announces nothing", func(t *testing.T) {
+		var service *LiveService
+		assert.NotPanics(t, func() { service.RatingsChanged(context.Background(), userID, movieID) })
+	})
+}
@@ -46,6 +46,11 @@ func (s *MovieService) GetMovieByID(ctx context.Context, movieID uuid.UUID) (*mo
 	return s.movieRepo.GetByID(ctx, movieID)
 }
 
+// GetMoviesByIDs returns the movies that exist out of ids, in no particular order
+func (s *MovieService) GetMoviesByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Movie, error) {
+	return s.movieRepo.GetByIDs(ctx, ids)
+}
+
 func (s *MovieService) GetMovieByTitle(ctx context.Context, title string) (*models.Movie, error) {
 	return s.movieRepo.GetByTitle(ctx, title)
 }
@@ -25,14 +25,41 @@ func (m *MockMovieRepository) GetMovies(ctx context.Context, searchTerm string,
 
 func (m *MockMovieRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Movie, error) {
 	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*models.Movie), args.Error(1)
 }
 
+func (m *MockMovieRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Movie, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Movie), args.Error(1)
+}
+
 func (m *MockMovieRepository) GetByTitle(ctx context.Context, title string) (*models.Movie, error) {
 	args := m.Called(ctx, title)
 	return args.Get(0).(*models.Movie), args.Error(1)
 }
 
+func (m *MockMovieRepository) GetByTitlesAndYears(ctx context.Context, keys []repository.TitleYear) ([]*models.Movie, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Movie), args.Error(1)
+}
+
+func (m *MockMovieRepository) GetByYearRange(ctx context.Context, fromYear, toYear int) ([]*models.Movie, error) {
+	args := m.Called(ctx, fromYear, toYear)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Movie), args.Error(1)
+}
+
 func (m *MockMovieRepository) GetSimilarMovies(ctx context.Context, embedding pgvector.Vector, page, pageSize int) (*repository.MoviePage, error) {
 	args := m.Called(ctx, embedding, page, pageSize)
 	return args.Get(0).(*repository.MoviePage), args.Error(1)
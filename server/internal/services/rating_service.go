@@ -2,19 +2,39 @@ package services
 
 import (
 	"context"
-	"errors"
-	"math"
+	"fmt"
 
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/models"
 	"github.com/Azanul/Next-Watch/internal/repository"
 	"github.com/google/uuid"
 	"github.com/pgvector/pgvector-go"
 )
 
+var errRatingNotFound = apperr.NotFound("rating not found")
+
+// MaxBatchRatings is how many movies RateMovies rates at once
+const MaxBatchRatings = 100
+
+// RatingInput is the score for a movie, an item of RateMovies
+type RatingInput struct {
+	MovieID uuid.UUID
+	Score   float32
+}
+
+// RatingResult is the outcome of an item of RateMovies, either the saved rating or why it wasn't saved
+type RatingResult struct {
+	MovieID uuid.UUID
+	Rating  *models.Rating
+	Err     error
+}
+
 type RatingService struct {
-	ratingRepo repository.RatingRepositoryInterface
-	movieRepo  repository.MovieRepositoryInterface
-	userRepo   repository.UserRepositoryInterface
+	ratingRepo     repository.RatingRepositoryInterface
+	movieRepo      repository.MovieRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	tasteWeighting TasteWeighting
+	live           *LiveService
 }
 
 func NewRatingService(ratingRepo repository.RatingRepositoryInterface, movieRepo repository.MovieRepositoryInterface, userRepo repository.UserRepositoryInterface) *RatingService {
@@ -25,6 +45,17 @@ func NewRatingService(ratingRepo repository.RatingRepositoryInterface, movieRepo
 	}
 }
 
+// SetTasteWeighting switches how scores are turned into taste vector weights
+func (s *RatingService) SetTasteWeighting(weighting TasteWeighting) {
+	s.tasteWeighting = weighting
+}
+
+// SetLiveService announces rating changes to subscriptions
+func (s *RatingService) SetLiveService(live *LiveService) {
+	s.live = live
+}
+
+// RateMovie saves the user's score for a movie together with their statistics and taste in one transaction
 func (s *RatingService) RateMovie(ctx context.Context, user *models.User, movieID uuid.UUID, score float32) (*models.Rating, error) {
 	// Validate movie exists
 	movie, err := s.movieRepo.GetByID(ctx, movieID)
@@ -32,7 +63,7 @@ func (s *RatingService) RateMovie(ctx context.Context, user *models.User, movieI
 		return nil, err
 	}
 	if movie == nil {
-		return nil, errors.New("movie not found")
+		return nil, apperr.NotFound("movie not found")
 	}
 
 	// Check for existing rating
@@ -41,65 +72,128 @@ func (s *RatingService) RateMovie(ctx context.Context, user *models.User, movieI
 		return nil, err
 	}
 
-	var rating *models.Rating
+	ratings, _, err := saveRatings(ctx, s.ratingRepo, s.tasteWeighting, user, map[uuid.UUID]*models.Rating{movieID: existingRating}, []scoredMovie{{movie: movie, score: score}})
+	if err != nil {
+		return nil, err
+	}
+	s.live.RatingsChanged(ctx, user.ID, movieID)
+
+	return ratings[0], nil
+}
+
+// RateMovies rates several movies at once, for onboarding. All items are validated first, then the valid ones
+// are saved in one transaction and the user's taste is updated once. Invalid items get an error in their
+// result without stopping the others, the results are in the order of inputs.
+func (s *RatingService) RateMovies(ctx context.Context, user *models.User, inputs []RatingInput) ([]*RatingResult, error) {
+	if len(inputs) > MaxBatchRatings {
+		return nil, apperr.Validation(fmt.Sprintf("at most %d movies can be rated at once", MaxBatchRatings))
+	}
 
-	if existingRating != nil {
-		// Update existing rating
-		existingRating.Score = score
-		err = s.ratingRepo.Update(ctx, existingRating)
-		if err != nil {
-			return nil, err
+	results := make([]*RatingResult, len(inputs))
+	var movieIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(inputs))
+	for i, input := range inputs {
+		results[i] = &RatingResult{MovieID: input.MovieID}
+		switch {
+		case input.Score < 0 || input.Score > 5:
+			results[i].Err = apperr.Validation("rating score must be between 0 and 5")
+		case seen[input.MovieID]:
+			results[i].Err = apperr.Validation("movie is rated more than once")
+		default:
+			seen[input.MovieID] = true
+			movieIDs = append(movieIDs, input.MovieID)
 		}
-		rating = existingRating
-	} else {
-		// Create new rating
-		newRating := &models.Rating{
-			UserID:  user.ID,
-			MovieID: movieID,
-			Score:   score,
+	}
+	if len(movieIDs) == 0 {
+		return results, nil
+	}
+
+	movies, err := s.movieRepo.GetByIDs(ctx, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+	moviesByID := make(map[uuid.UUID]*models.Movie, len(movies))
+	for _, movie := range movies {
+		moviesByID[movie.ID] = movie
+	}
+	existingRatings, err := s.ratingRepo.GetByUserAndMovies(ctx, user.ID, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var scored []scoredMovie
+	var scoredResults []*RatingResult
+	for i, input := range inputs {
+		result := results[i]
+		if result.Err != nil {
+			continue
 		}
-		err = s.ratingRepo.Create(ctx, newRating)
-		if err != nil {
-			return nil, err
+		if moviesByID[input.MovieID] == nil {
+			result.Err = apperr.NotFound("movie not found")
+			continue
 		}
-		rating = newRating
+		scored = append(scored, scoredMovie{movie: moviesByID[input.MovieID], score: input.Score})
+		scoredResults = append(scoredResults, result)
+	}
+	if len(scored) == 0 {
+		return results, nil
 	}
 
-	// Update user's taste
-	err = s.updateUserTaste(ctx, user, movie, score)
+	ratings, movieIDs, err := saveRatings(ctx, s.ratingRepo, s.tasteWeighting, user, existingRatings, scored)
 	if err != nil {
 		return nil, err
 	}
+	for i, result := range scoredResults {
+		result.Rating = ratings[i]
+	}
+	s.live.RatingsChanged(ctx, user.ID, movieIDs...)
 
-	return rating, nil
+	return results, nil
 }
 
-func (s *RatingService) updateUserTaste(ctx context.Context, user *models.User, movie *models.Movie, score float32) error {
-	tasteVector, movieEmbeddingVector := user.Taste.Slice(), movie.Embedding.Slice()
-	if len(tasteVector) != len(movieEmbeddingVector) {
-		return errors.New("taste and embedding dimensions do not match")
-	}
-
-	// Calculate the weight based on the score
-	weight := (score - 2.5) / 2.5 // Normalize score to [-1, 1]
+// scoredMovie is a score to save for a movie
+type scoredMovie struct {
+	movie *models.Movie
+	score float32
+}
 
-	for i := range tasteVector {
-		tasteVector[i] += weight * movieEmbeddingVector[i]
+// saveRatings saves the scores of movies as the user's ratings, updating the existing ones, together with
+// the user's statistics and taste in one transaction. The user is only changed once everything is saved.
+// It returns the ratings in the order of scored and the IDs of their movies.
+func saveRatings(ctx context.Context, ratingRepo repository.RatingRepositoryInterface, weighting TasteWeighting, user *models.User, existingRatings map[uuid.UUID]*models.Rating, scored []scoredMovie) ([]*models.Rating, []uuid.UUID, error) {
+	ratings := make([]*models.Rating, len(scored))
+	movieIDs := make([]uuid.UUID, len(scored))
+	scoreStats := user.ScoreStats
+	for i, sm := range scored {
+		if existingRating := existingRatings[sm.movie.ID]; existingRating != nil {
+			scoreStats = replaceScore(scoreStats, existingRating.Score, sm.score)
+			existingRating.Score = sm.score
+			ratings[i] = existingRating
+		} else {
+			scoreStats = addScore(scoreStats, sm.score)
+			ratings[i] = &models.Rating{UserID: user.ID, MovieID: sm.movie.ID, Score: sm.score}
+		}
+		movieIDs[i] = sm.movie.ID
 	}
 
-	// Normalize the taste vector
-	magnitude := float32(0)
-	for _, v := range tasteVector {
-		magnitude += v * v
+	// Weights are computed against the final statistics so the order of the items doesn't matter.
+	// The taste is copied, the user's own vector is only replaced once the ratings are saved.
+	tasteVector := append([]float32(nil), user.Taste.Slice()...)
+	for _, sm := range scored {
+		if err := addToTaste(tasteVector, sm.movie.Embedding.Slice(), tasteWeight(weighting, scoreStats, sm.score)); err != nil {
+			return nil, nil, err
+		}
 	}
-	magnitude = float32(math.Sqrt(float64(magnitude)))
+	normalizeTaste(tasteVector)
 
-	for i := range tasteVector {
-		tasteVector[i] /= magnitude
+	updatedUser := *user
+	updatedUser.ScoreStats = scoreStats
+	updatedUser.Taste = pgvector.NewVector(tasteVector)
+	if err := ratingRepo.SaveBatch(ctx, &updatedUser, ratings); err != nil {
+		return nil, nil, err
 	}
-	user.Taste = pgvector.NewVector(tasteVector)
-
-	return s.userRepo.Update(ctx, user)
+	user.ScoreStats, user.Taste = updatedUser.ScoreStats, updatedUser.Taste
+	return ratings, movieIDs, nil
 }
 
 func (s *RatingService) GetRatingByID(ctx context.Context, ratingID uuid.UUID) (*models.Rating, error) {
@@ -107,6 +201,9 @@ func (s *RatingService) GetRatingByID(ctx context.Context, ratingID uuid.UUID) (
 	if err != nil {
 		return nil, err
 	}
+	if rating == nil {
+		return nil, errRatingNotFound
+	}
 
 	return &models.Rating{
 		ID:      ratingID,
@@ -116,11 +213,23 @@ func (s *RatingService) GetRatingByID(ctx context.Context, ratingID uuid.UUID) (
 	}, nil
 }
 
+func (s *RatingService) GetRatingsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Rating, error) {
+	return s.ratingRepo.GetByIDs(ctx, ids)
+}
+
+// GetRatingsByUser returns the user's ratings, most recently updated first
+func (s *RatingService) GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]*models.Rating, error) {
+	return s.ratingRepo.GetByUser(ctx, userID)
+}
+
 func (s *RatingService) GetRatingByUserAndMovie(ctx context.Context, userID, movieID uuid.UUID) (*models.Rating, error) {
 	rating, err := s.ratingRepo.GetByUserAndMovie(ctx, userID, movieID)
 	if err != nil {
 		return nil, err
 	}
+	if rating == nil {
+		return nil, errRatingNotFound
+	}
 
 	return &models.Rating{
 		ID:      rating.ID,
@@ -130,10 +239,33 @@ func (s *RatingService) GetRatingByUserAndMovie(ctx context.Context, userID, mov
 	}, nil
 }
 
+// DeleteRating removes a rating and takes its score back out of the owner's statistics, which the taste
+// weighting of their next ratings is based on
 func (s *RatingService) DeleteRating(ctx context.Context, ratingID uuid.UUID) (bool, error) {
-	_, err := s.ratingRepo.Delete(ctx, ratingID)
+	rating, err := s.ratingRepo.GetByID(ctx, ratingID)
+	if err != nil {
+		return false, err
+	}
+	if rating == nil {
+		return false, errRatingNotFound
+	}
+	// Ratings are deleted with their owner, so a missing owner means the rating is gone too
+	owner, err := s.userRepo.GetByID(ctx, rating.UserID)
+	if err != nil {
+		return false, err
+	}
+	if owner == nil {
+		return false, errRatingNotFound
+	}
+
+	owner.ScoreStats = removeScore(owner.ScoreStats, rating.Score)
+	deleted, err := s.ratingRepo.Delete(ctx, rating.ID, owner)
 	if err != nil {
-		return false, nil
+		return false, err
+	}
+	if !deleted {
+		return false, errRatingNotFound
 	}
+	s.live.RatingsChanged(ctx, rating.UserID, rating.MovieID)
 	return true, nil
 }
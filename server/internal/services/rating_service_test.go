@@ -5,7 +5,9 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
 	"github.com/google/uuid"
 	"github.com/pgvector/pgvector-go"
 	"github.com/stretchr/testify/assert"
@@ -24,6 +26,22 @@ func (m *MockRatingRepository) GetByID(ctx context.Context, ratingID uuid.UUID)
 	return args.Get(0).(*models.Rating), args.Error(1)
 }
 
+func (m *MockRatingRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Rating, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Rating), args.Error(1)
+}
+
+func (m *MockRatingRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Rating, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Rating), args.Error(1)
+}
+
 func (m *MockRatingRepository) GetByUserAndMovie(ctx context.Context, userID, movieID uuid.UUID) (*models.Rating, error) {
 	args := m.Called(ctx, userID, movieID)
 	if args.Get(0) == nil {
@@ -32,6 +50,14 @@ func (m *MockRatingRepository) GetByUserAndMovie(ctx context.Context, userID, mo
 	return args.Get(0).(*models.Rating), args.Error(1)
 }
 
+func (m *MockRatingRepository) GetByUserAndMovies(ctx context.Context, userID uuid.UUID, movieIDs []uuid.UUID) (map[uuid.UUID]*models.Rating, error) {
+	args := m.Called(ctx, userID, movieIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]*models.Rating), args.Error(1)
+}
+
 func (m *MockRatingRepository) Create(ctx context.Context, rating *models.Rating) error {
 	args := m.Called(ctx, rating)
 	return args.Error(0)
@@ -42,12 +68,58 @@ func (m *MockRatingRepository) Update(ctx context.Context, rating *models.Rating
 	return args.Error(0)
 }
 
-func (m *MockRatingRepository) Delete(ctx context.Context, ratingID uuid.UUID) (*models.Rating, error) {
-	args := m.Called(ctx, ratingID)
+func (m *MockRatingRepository) SaveBatch(ctx context.Context, user *models.User, ratings []*models.Rating) error {
+	args := m.Called(ctx, user, ratings)
+	return args.Error(0)
+}
+
+func (m *MockRatingRepository) Delete(ctx context.Context, ratingID uuid.UUID, owner *models.User) (bool, error) {
+	args := m.Called(ctx, ratingID, owner)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRatingRepository) ForEachByUser(ctx context.Context, userID uuid.UUID, fn func(*repository.RatedMovie) error) error {
+	args := m.Called(ctx, userID)
+	if ratedMovies, ok := args.Get(0).([]*repository.RatedMovie); ok {
+		for _, rm := range ratedMovies {
+			if err := fn(rm); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRatingRepository) GetSummaryByUser(ctx context.Context, userID uuid.UUID) (*repository.RatingSummary, error) {
+	args := m.Called(ctx, userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.Rating), args.Error(1)
+	return args.Get(0).(*repository.RatingSummary), args.Error(1)
+}
+
+func (m *MockRatingRepository) GetSummariesByUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*repository.RatingSummary, error) {
+	args := m.Called(ctx, userIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]*repository.RatingSummary), args.Error(1)
+}
+
+func (m *MockRatingRepository) GetSummaryByMovie(ctx context.Context, movieID uuid.UUID) (*repository.RatingSummary, error) {
+	args := m.Called(ctx, movieID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.RatingSummary), args.Error(1)
+}
+
+func (m *MockRatingRepository) GetTopGenresByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*repository.GenreSummary, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repository.GenreSummary), args.Error(1)
 }
 
 func TestRatingService_RateMovie(t *testing.T) {
@@ -72,8 +144,12 @@ func TestRatingService_RateMovie(t *testing.T) {
 			mockSetup: func() {
 				mockMovieRepo.On("GetByID", ctx, movieID).Return(&models.Movie{ID: movieID, Embedding: pgvector.NewVector(make([]float32, 512))}, nil)
 				mockRatingRepo.On("GetByUserAndMovie", ctx, user.ID, movieID).Return(nil, nil)
-				mockRatingRepo.On("Create", ctx, mock.AnythingOfType("*models.Rating")).Return(nil)
-				mockUserRepo.On("Update", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+				// The rating and the statistics counting it are saved together
+				mockRatingRepo.On("SaveBatch", ctx, mock.MatchedBy(func(u *models.User) bool {
+					return u.ScoreStats.Count == 1 && u.ScoreStats.Mean == 4.5
+				}), mock.MatchedBy(func(ratings []*models.Rating) bool {
+					return len(ratings) == 1 && ratings[0].ID == uuid.Nil
+				})).Return(nil)
 			},
 			want:    &models.Rating{UserID: user.ID, MovieID: movieID, Score: score},
 			wantErr: false,
@@ -82,9 +158,12 @@ func TestRatingService_RateMovie(t *testing.T) {
 			name: "Success - Update Existing Rating",
 			mockSetup: func() {
 				mockMovieRepo.On("GetByID", ctx, movieID).Return(&models.Movie{ID: movieID, Embedding: pgvector.NewVector(make([]float32, 512))}, nil)
-				mockRatingRepo.On("GetByUserAndMovie", ctx, user.ID, movieID).Return(&models.Rating{ID: uuid.New(), UserID: user.ID, MovieID: movieID, Score: 3.0}, nil)
-				mockRatingRepo.On("Update", ctx, mock.AnythingOfType("*models.Rating")).Return(nil)
-				mockUserRepo.On("Update", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+				mockRatingRepo.On("GetByUserAndMovie", ctx, user.ID, movieID).Return(&models.Rating{ID: uuid.New(), UserID: user.ID, MovieID: movieID, Score: 4.5}, nil)
+				mockRatingRepo.On("SaveBatch", ctx, mock.MatchedBy(func(u *models.User) bool {
+					return u.ScoreStats.Count == 1
+				}), mock.MatchedBy(func(ratings []*models.Rating) bool {
+					return len(ratings) == 1 && ratings[0].ID != uuid.Nil
+				})).Return(nil)
 			},
 			want:    &models.Rating{UserID: user.ID, MovieID: movieID, Score: score},
 			wantErr: false,
@@ -97,19 +176,36 @@ func TestRatingService_RateMovie(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
-		// Add more test cases as needed
+		{
+			name: "Error - Save Fails",
+			mockSetup: func() {
+				mockMovieRepo.On("GetByID", ctx, movieID).Return(&models.Movie{ID: movieID, Embedding: pgvector.NewVector(make([]float32, 512))}, nil)
+				mockRatingRepo.On("GetByUserAndMovie", ctx, user.ID, movieID).Return(nil, nil)
+				mockRatingRepo.On("SaveBatch", ctx, mock.AnythingOfType("*models.User"), mock.AnythingOfType("[]*models.Rating")).Return(errors.New("database error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			// Expectations of earlier cases would otherwise answer first
+			mockRatingRepo.ExpectedCalls = nil
+			mockMovieRepo.ExpectedCalls = nil
+			mockUserRepo.ExpectedCalls = nil
 			tt.mockSetup()
 
+			stats := user.ScoreStats
 			got, err := service.RateMovie(ctx, user, movieID, score)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RatingService.RateMovie() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
+			if tt.wantErr {
+				// The statistics only change once the rating is saved
+				assert.Equal(t, stats, user.ScoreStats)
+			} else {
 				assert.Equal(t, tt.want.UserID, got.UserID)
 				assert.Equal(t, tt.want.MovieID, got.MovieID)
 				assert.Equal(t, tt.want.Score, got.Score)
@@ -118,6 +214,76 @@ func TestRatingService_RateMovie(t *testing.T) {
 	}
 }
 
+func TestRatingService_RateMovies(t *testing.T) {
+	ctx := context.Background()
+	embedding := make([]float32, 512)
+	embedding[0] = 1
+	newMovie := &models.Movie{ID: uuid.New(), Embedding: pgvector.NewVector(embedding)}
+	ratedMovie := &models.Movie{ID: uuid.New(), Embedding: pgvector.NewVector(embedding)}
+	missingMovieID := uuid.New()
+
+	t.Run("Saves Valid Items In One Batch", func(t *testing.T) {
+		mockRatingRepo := new(MockRatingRepository)
+		mockMovieRepo := new(MockMovieRepository)
+		service := NewRatingService(mockRatingRepo, mockMovieRepo, nil)
+		user := &models.User{ID: uuid.New(), Taste: pgvector.NewVector(make([]float32, 512))}
+		existingRating := &models.Rating{ID: uuid.New(), UserID: user.ID, MovieID: ratedMovie.ID, Score: 2}
+		user.ScoreStats = addScore(user.ScoreStats, existingRating.Score)
+
+		movieIDs := []uuid.UUID{newMovie.ID, ratedMovie.ID, missingMovieID}
+		mockMovieRepo.On("GetByIDs", ctx, movieIDs).Return([]*models.Movie{newMovie, ratedMovie}, nil)
+		mockRatingRepo.On("GetByUserAndMovies", ctx, user.ID, movieIDs).Return(map[uuid.UUID]*models.Rating{ratedMovie.ID: existingRating}, nil)
+		mockRatingRepo.On("SaveBatch", ctx, mock.AnythingOfType("*models.User"), mock.AnythingOfType("[]*models.Rating")).Return(nil)
+
+		results, err := service.RateMovies(ctx, user, []RatingInput{
+			{MovieID: newMovie.ID, Score: 5},
+			{MovieID: ratedMovie.ID, Score: 4},
+			{MovieID: missingMovieID, Score: 3},
+			{MovieID: newMovie.ID, Score: 1},
+			{MovieID: uuid.New(), Score: 6},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, results, 5)
+
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, float32(5), results[0].Rating.Score)
+		assert.NoError(t, results[1].Err)
+		assert.Equal(t, existingRating.ID, results[1].Rating.ID)
+		assert.Equal(t, float32(4), results[1].Rating.Score)
+		assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(results[2].Err))
+		assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(results[3].Err))
+		assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(results[4].Err))
+
+		saved := mockRatingRepo.Calls[1].Arguments.Get(2).([]*models.Rating)
+		assert.Len(t, saved, 2)
+		assert.Equal(t, 2, user.ScoreStats.Count)
+		assert.Equal(t, float32(1), user.Taste.Slice()[0])
+		mockRatingRepo.AssertNumberOfCalls(t, "SaveBatch", 1)
+	})
+
+	t.Run("Leaves The User Alone When Saving Fails", func(t *testing.T) {
+		mockRatingRepo := new(MockRatingRepository)
+		mockMovieRepo := new(MockMovieRepository)
+		service := NewRatingService(mockRatingRepo, mockMovieRepo, nil)
+		user := &models.User{ID: uuid.New(), Taste: pgvector.NewVector(make([]float32, 512))}
+
+		mockMovieRepo.On("GetByIDs", ctx, []uuid.UUID{newMovie.ID}).Return([]*models.Movie{newMovie}, nil)
+		mockRatingRepo.On("GetByUserAndMovies", ctx, user.ID, []uuid.UUID{newMovie.ID}).Return(map[uuid.UUID]*models.Rating{}, nil)
+		mockRatingRepo.On("SaveBatch", ctx, mock.AnythingOfType("*models.User"), mock.AnythingOfType("[]*models.Rating")).Return(errors.New("database error"))
+
+		_, err := service.RateMovies(ctx, user, []RatingInput{{MovieID: newMovie.ID, Score: 5}})
+		assert.Error(t, err)
+		assert.Equal(t, 0, user.ScoreStats.Count)
+		assert.Equal(t, float32(0), user.Taste.Slice()[0])
+	})
+
+	t.Run("Too Many Items", func(t *testing.T) {
+		service := NewRatingService(new(MockRatingRepository), new(MockMovieRepository), nil)
+		_, err := service.RateMovies(ctx, &models.User{}, make([]RatingInput, MaxBatchRatings+1))
+		assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+	})
+}
+
 func TestRatingService_GetRatingByID(t *testing.T) {
 	mockRatingRepo := new(MockRatingRepository)
 	service := NewRatingService(mockRatingRepo, nil, nil)
@@ -157,6 +323,7 @@ func TestRatingService_GetRatingByID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo.ExpectedCalls = nil
 			tt.mockSetup()
 
 			got, err := service.GetRatingByID(ctx, ratingID)
@@ -212,6 +379,7 @@ func TestRatingService_GetRatingByUserAndMovie(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo.ExpectedCalls = nil
 			tt.mockSetup()
 
 			got, err := service.GetRatingByUserAndMovie(ctx, userID, movieID)
@@ -230,10 +398,20 @@ func TestRatingService_GetRatingByUserAndMovie(t *testing.T) {
 
 func TestRatingService_DeleteRating(t *testing.T) {
 	mockRatingRepo := new(MockRatingRepository)
-	service := NewRatingService(mockRatingRepo, nil, nil)
+	mockUserRepo := new(MockUserRepository)
+	service := NewRatingService(mockRatingRepo, nil, mockUserRepo)
 
 	ctx := context.Background()
 	ratingID := uuid.New()
+	ownerID := uuid.New()
+	rating := &models.Rating{ID: ratingID, UserID: ownerID, MovieID: uuid.New(), Score: 5}
+
+	// The owner rated 3 and 5 before, the 5 is deleted
+	ownerStats := addScore(addScore(models.ScoreStats{}, 3), 5)
+	owner := func() *models.User { return &models.User{ID: ownerID, ScoreStats: ownerStats} }
+	withoutDeleted := mock.MatchedBy(func(user *models.User) bool {
+		return user.ID == ownerID && user.ScoreStats.Count == 1 && user.ScoreStats.Mean == 3 && user.ScoreStats.M2 == 0
+	})
 
 	tests := []struct {
 		name      string
@@ -244,7 +422,9 @@ func TestRatingService_DeleteRating(t *testing.T) {
 		{
 			name: "Success",
 			mockSetup: func() {
-				mockRatingRepo.On("Delete", ctx, ratingID).Return(&models.Rating{}, nil)
+				mockRatingRepo.On("GetByID", ctx, ratingID).Return(rating, nil)
+				mockUserRepo.On("GetByID", ctx, ownerID).Return(owner(), nil)
+				mockRatingRepo.On("Delete", ctx, ratingID, withoutDeleted).Return(true, nil)
 			},
 			want:    true,
 			wantErr: false,
@@ -252,16 +432,37 @@ func TestRatingService_DeleteRating(t *testing.T) {
 		{
 			name: "Error",
 			mockSetup: func() {
-				mockRatingRepo.On("Delete", ctx, ratingID).Return(nil, errors.New("database error"))
+				mockRatingRepo.On("GetByID", ctx, ratingID).Return(rating, nil)
+				mockUserRepo.On("GetByID", ctx, ownerID).Return(owner(), nil)
+				mockRatingRepo.On("Delete", ctx, ratingID, withoutDeleted).Return(false, errors.New("database error"))
+			},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mockRatingRepo.On("GetByID", ctx, ratingID).Return(nil, nil)
+			},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name: "Deleted Concurrently",
+			mockSetup: func() {
+				mockRatingRepo.On("GetByID", ctx, ratingID).Return(rating, nil)
+				mockUserRepo.On("GetByID", ctx, ownerID).Return(owner(), nil)
+				mockRatingRepo.On("Delete", ctx, ratingID, withoutDeleted).Return(false, nil)
 			},
 			want:    false,
 			wantErr: true,
 		},
-		// Add more test cases as needed
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockRatingRepo.ExpectedCalls = nil
+			mockUserRepo.ExpectedCalls = nil
 			tt.mockSetup()
 
 			got, err := service.DeleteRating(ctx, ratingID)
@@ -270,6 +471,8 @@ func TestRatingService_DeleteRating(t *testing.T) {
 				return
 			}
 			assert.Equal(t, tt.want, got)
+			mockRatingRepo.AssertExpectations(t)
+			mockUserRepo.AssertExpectations(t)
 		})
 	}
 }
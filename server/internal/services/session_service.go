@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/apperr"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultSessionIdleTimeout     = 7 * 24 * time.Hour
+	DefaultSessionAbsoluteTimeout = 30 * 24 * time.Hour
+
+	// last_seen_at is only written once it is this old, so not every request costs a write
+	sessionTouchInterval = time.Minute
+)
+
+var ErrInvalidSession = apperr.Unauthenticated("invalid or expired session")
+
+type SessionService struct {
+	sessionRepo     repository.SessionRepositoryInterface
+	userRepo        repository.UserRepositoryInterface
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+}
+
+func NewSessionService(sessionRepo repository.SessionRepositoryInterface, userRepo repository.UserRepositoryInterface) *SessionService {
+	return &SessionService{
+		sessionRepo:     sessionRepo,
+		userRepo:        userRepo,
+		idleTimeout:     DefaultSessionIdleTimeout,
+		absoluteTimeout: DefaultSessionAbsoluteTimeout,
+	}
+}
+
+// SetTimeouts changes how long a session may go unused and how long it may live at all
+func (s *SessionService) SetTimeouts(idle, absolute time.Duration) error {
+	if idle <= 0 || absolute <= 0 {
+		return errors.New("session timeouts must be positive")
+	}
+	if idle > absolute {
+		return errors.New("session idle timeout cannot exceed the absolute timeout")
+	}
+	s.idleTimeout, s.absoluteTimeout = idle, absolute
+	return nil
+}
+
+// AbsoluteTimeout is the longest a session can live, used as the cookie lifetime
+func (s *SessionService) AbsoluteTimeout() time.Duration {
+	return s.absoluteTimeout
+}
+
+// StartSession creates a session for the user and returns the token to hand to the client.
+// The token itself is never stored.
+func (s *SessionService) StartSession(ctx context.Context, user *models.User, userAgent, ipAddress string) (string, *models.Session, error) {
+	token, err := newToken("")
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.absoluteTimeout),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Authenticate returns the user and session a token belongs to, or ErrInvalidSession
+// if it is unknown, revoked or expired
+func (s *SessionService) Authenticate(ctx context.Context, token string) (*models.User, *models.Session, error) {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if session == nil || session.RevokedAt != nil || !now.Before(s.SessionExpiry(session)) {
+		return nil, nil, ErrInvalidSession
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidSession
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			return nil, nil, err
+		}
+		session.LastSeenAt = now
+	}
+	return user, session, nil
+}
+
+// SessionExpiry is when the session ends unless it is used again before then
+func (s *SessionService) SessionExpiry(session *models.Session) time.Time {
+	idleExpiry := session.LastSeenAt.Add(s.idleTimeout)
+	if idleExpiry.Before(session.ExpiresAt) {
+		return idleExpiry
+	}
+	return session.ExpiresAt
+}
+
+// EndSession revokes the session a token belongs to. Unknown tokens are ignored.
+func (s *SessionService) EndSession(ctx context.Context, token string) error {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil || session == nil {
+		return err
+	}
+	_, err = s.sessionRepo.Revoke(ctx, session.ID, session.UserID, time.Now())
+	return err
+}
+
+// ListSessions returns the user's sessions that can still be used
+func (s *SessionService) ListSessions(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*models.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if now.Before(s.SessionExpiry(session)) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession signs one of the user's devices out. It reports false if the session
+// doesn't exist, belongs to someone else or was already revoked.
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	return s.sessionRepo.Revoke(ctx, sessionID, userID, time.Now())
+}
+
+// newToken returns a random, URL safe token starting with prefix
+func newToken(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken is what is stored in place of a session or access token
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetByTokenHash(ctx context.Context, tokenHash []byte) (*models.Session, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	args := m.Called(ctx, userID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	args := m.Called(ctx, id, lastSeenAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) Revoke(ctx context.Context, id, userID uuid.UUID, revokedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, userID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	args := m.Called(ctx, userID, revokedAt)
+	return args.Error(0)
+}
+
+func TestSessionService_StartSession(t *testing.T) {
+	ctx := context.Background()
+	mockSessionRepo := new(MockSessionRepository)
+	service := NewSessionService(mockSessionRepo, nil)
+	user := &models.User{ID: uuid.New()}
+
+	mockSessionRepo.On("Create", ctx, mock.AnythingOfType("*models.Session")).Return(nil)
+
+	token, session, err := service.StartSession(ctx, user, "Firefox", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashToken(token), session.TokenHash)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, session.CreatedAt.Add(DefaultSessionAbsoluteTimeout), session.ExpiresAt)
+}
+
+func TestSessionService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New()}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		session   *models.Session
+		mockSetup func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session)
+		wantErr   error
+	}{
+		{
+			name:    "Success - Recently Used",
+			session: &models.Session{ID: uuid.New(), UserID: user.ID, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {
+				userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "Success - Touched",
+			session: &models.Session{ID: uuid.New(), UserID: user.ID, LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {
+				userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+				sessionRepo.On("Touch", ctx, session.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Error - Unknown Token",
+			session:   nil,
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {},
+			wantErr:   ErrInvalidSession,
+		},
+		{
+			name:      "Error - Revoked",
+			session:   &models.Session{ID: uuid.New(), UserID: user.ID, LastSeenAt: now, ExpiresAt: now.Add(time.Hour), RevokedAt: &now},
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {},
+			wantErr:   ErrInvalidSession,
+		},
+		{
+			name:      "Error - Idle Too Long",
+			session:   &models.Session{ID: uuid.New(), UserID: user.ID, LastSeenAt: now.Add(-DefaultSessionIdleTimeout - time.Minute), ExpiresAt: now.Add(time.Hour)},
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {},
+			wantErr:   ErrInvalidSession,
+		},
+		{
+			name:      "Error - Past Absolute Expiry",
+			session:   &models.Session{ID: uuid.New(), UserID: user.ID, LastSeenAt: now, ExpiresAt: now.Add(-time.Second)},
+			mockSetup: func(sessionRepo *MockSessionRepository, userRepo *MockUserRepository, session *models.Session) {},
+			wantErr:   ErrInvalidSession,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessionRepo := new(MockSessionRepository)
+			mockUserRepo := new(MockUserRepository)
+			service := NewSessionService(mockSessionRepo, mockUserRepo)
+			if tt.session != nil {
+				mockSessionRepo.On("GetByTokenHash", ctx, hashToken("token")).Return(tt.session, nil)
+			} else {
+				mockSessionRepo.On("GetByTokenHash", ctx, hashToken("token")).Return(nil, nil)
+			}
+			tt.mockSetup(mockSessionRepo, mockUserRepo, tt.session)
+
+			got, _, err := service.Authenticate(ctx, "token")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SessionService.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				assert.Equal(t, user, got)
+			}
+			mockSessionRepo.AssertExpectations(t)
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionService_ListSessions(t *testing.T) {
+	ctx := context.Background()
+	mockSessionRepo := new(MockSessionRepository)
+	service := NewSessionService(mockSessionRepo, nil)
+	userID := uuid.New()
+	now := time.Now()
+
+	active := &models.Session{ID: uuid.New(), LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+	idle := &models.Session{ID: uuid.New(), LastSeenAt: now.Add(-DefaultSessionIdleTimeout - time.Minute), ExpiresAt: now.Add(time.Hour)}
+	mockSessionRepo.On("ListActiveByUser", ctx, userID, mock.AnythingOfType("time.Time")).Return([]*models.Session{active, idle}, nil)
+
+	sessions, err := service.ListSessions(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Session{active}, sessions)
+}
+
+func TestSessionService_EndSession(t *testing.T) {
+	ctx := context.Background()
+	mockSessionRepo := new(MockSessionRepository)
+	service := NewSessionService(mockSessionRepo, nil)
+	session := &models.Session{ID: uuid.New(), UserID: uuid.New()}
+
+	mockSessionRepo.On("GetByTokenHash", ctx, hashToken("token")).Return(session, nil)
+	mockSessionRepo.On("GetByTokenHash", ctx, hashToken("unknown")).Return(nil, nil)
+	mockSessionRepo.On("Revoke", ctx, session.ID, session.UserID, mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+
+	assert.NoError(t, service.EndSession(ctx, "token"))
+	assert.NoError(t, service.EndSession(ctx, "unknown"))
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestSessionService_SetTimeouts(t *testing.T) {
+	service := NewSessionService(nil, nil)
+
+	assert.Error(t, service.SetTimeouts(0, time.Hour))
+	assert.Error(t, service.SetTimeouts(2*time.Hour, time.Hour))
+	assert.NoError(t, service.SetTimeouts(time.Hour, 2*time.Hour))
+	assert.Equal(t, 2*time.Hour, service.AbsoluteTimeout())
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+)
+
+// TasteWeighting decides how much a score pulls the taste vector towards a movie
+type TasteWeighting int
+
+const (
+	// LegacyTasteWeighting centres every user's scores on 2.5
+	LegacyTasteWeighting TasteWeighting = iota
+	// NormalizedTasteWeighting centres scores on the user's own mean and scales them by their spread
+	NormalizedTasteWeighting
+)
+
+const (
+	// Below this many ratings the user's mean is too noisy and legacy weighting is used
+	minRatingsForNormalization = 3
+	// Keeps users who give (almost) every movie the same score from getting huge weights
+	minScoreStdDev = 0.5
+)
+
+func ParseTasteWeighting(name string) (TasteWeighting, error) {
+	switch name {
+	case "", "legacy":
+		return LegacyTasteWeighting, nil
+	case "normalized":
+		return NormalizedTasteWeighting, nil
+	default:
+		return LegacyTasteWeighting, fmt.Errorf("unknown taste weighting %q", name)
+	}
+}
+
+// tasteWeight maps a 0-5 score to a weight in [-1, 1]
+func tasteWeight(weighting TasteWeighting, stats models.ScoreStats, score float32) float32 {
+	if weighting == LegacyTasteWeighting || stats.Count < minRatingsForNormalization {
+		return scoreWeight(score)
+	}
+
+	stdDev := math.Max(math.Sqrt(stats.M2/float64(stats.Count)), minScoreStdDev)
+	weight := (float64(score) - stats.Mean) / (2 * stdDev)
+	return float32(math.Max(-1, math.Min(1, weight)))
+}
+
+// scoreWeight maps a 0-5 score to the weight its movie gets in the taste vector
+func scoreWeight(score float32) float32 {
+	return (score - 2.5) / 2.5 // Normalize score to [-1, 1]
+}
+
+// addScore adds a score to the running statistics
+func addScore(stats models.ScoreStats, score float32) models.ScoreStats {
+	x := float64(score)
+	stats.Count++
+	delta := x - stats.Mean
+	stats.Mean += delta / float64(stats.Count)
+	stats.M2 += delta * (x - stats.Mean)
+	return stats
+}
+
+// removeScore takes a previously added score back out of the running statistics
+func removeScore(stats models.ScoreStats, score float32) models.ScoreStats {
+	if stats.Count <= 1 {
+		return models.ScoreStats{}
+	}
+
+	x := float64(score)
+	previousMean := (float64(stats.Count)*stats.Mean - x) / float64(stats.Count-1)
+	stats.M2 = math.Max(0, stats.M2-(x-previousMean)*(x-stats.Mean))
+	stats.Mean = previousMean
+	stats.Count--
+	return stats
+}
+
+// replaceScore updates the running statistics when a rating's score changes
+func replaceScore(stats models.ScoreStats, oldScore, newScore float32) models.ScoreStats {
+	return addScore(removeScore(stats, oldScore), newScore)
+}
+
+// addToTaste moves the taste vector towards (or away from) a movie embedding
+func addToTaste(tasteVector, movieEmbeddingVector []float32, weight float32) error {
+	if len(tasteVector) != len(movieEmbeddingVector) {
+		return errors.New("taste and embedding dimensions do not match")
+	}
+
+	for i := range tasteVector {
+		tasteVector[i] += weight * movieEmbeddingVector[i]
+	}
+	return nil
+}
+
+// normalizeTaste scales the taste vector to unit length
+func normalizeTaste(tasteVector []float32) {
+	magnitude := float32(0)
+	for _, v := range tasteVector {
+		magnitude += v * v
+	}
+	magnitude = float32(math.Sqrt(float64(magnitude)))
+	if magnitude == 0 {
+		return
+	}
+
+	for i := range tasteVector {
+		tasteVector[i] /= magnitude
+	}
+}
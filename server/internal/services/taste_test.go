@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func statsOf(scores ...float32) models.ScoreStats {
+	var stats models.ScoreStats
+	for _, score := range scores {
+		stats = addScore(stats, score)
+	}
+	return stats
+}
+
+func TestScoreStats(t *testing.T) {
+	scores := []float32{1.5, 2, 2, 2.5, 3}
+	stats := statsOf(scores...)
+
+	mean, m2 := 0.0, 0.0
+	for _, score := range scores {
+		mean += float64(score)
+	}
+	mean /= float64(len(scores))
+	for _, score := range scores {
+		m2 += (float64(score) - mean) * (float64(score) - mean)
+	}
+
+	assert.Equal(t, 5, stats.Count)
+	assert.InDelta(t, mean, stats.Mean, 1e-9)
+	assert.InDelta(t, m2, stats.M2, 1e-9)
+
+	// Removing a score must give the same result as never adding it
+	removed := removeScore(stats, 3)
+	expected := statsOf(1.5, 2, 2, 2.5)
+	assert.Equal(t, expected.Count, removed.Count)
+	assert.InDelta(t, expected.Mean, removed.Mean, 1e-9)
+	assert.InDelta(t, expected.M2, removed.M2, 1e-9)
+
+	replaced := replaceScore(stats, 1.5, 4)
+	expected = statsOf(2, 2, 2.5, 3, 4)
+	assert.InDelta(t, expected.Mean, replaced.Mean, 1e-9)
+	assert.InDelta(t, expected.M2, replaced.M2, 1e-9)
+
+	assert.Equal(t, models.ScoreStats{}, removeScore(statsOf(4), 4))
+}
+
+func TestTasteWeight(t *testing.T) {
+	harshCritic := statsOf(1, 1.5, 1.5, 2, 2, 2.5, 3)
+	generous := statsOf(4, 4.5, 4.5, 5, 5)
+
+	tests := []struct {
+		name           string
+		stats          models.ScoreStats
+		score          float32
+		wantLegacy     float32
+		wantNormalized func(t *testing.T, weight float32)
+	}{
+		{
+			name:       "Harsh critic's 3.0 is a favourite",
+			stats:      harshCritic,
+			score:      3,
+			wantLegacy: 0.2,
+			wantNormalized: func(t *testing.T, weight float32) {
+				assert.Greater(t, weight, float32(0.5))
+			},
+		},
+		{
+			name:       "Harsh critic's average score is neutral",
+			stats:      harshCritic,
+			score:      float32(harshCritic.Mean),
+			wantLegacy: (float32(harshCritic.Mean) - 2.5) / 2.5,
+			wantNormalized: func(t *testing.T, weight float32) {
+				assert.InDelta(t, 0, weight, 1e-6)
+			},
+		},
+		{
+			name:       "Generous user's 4.0 is a dislike",
+			stats:      generous,
+			score:      4,
+			wantLegacy: 0.6,
+			wantNormalized: func(t *testing.T, weight float32) {
+				assert.Less(t, weight, float32(0))
+			},
+		},
+		{
+			name:       "Too few ratings falls back to legacy",
+			stats:      statsOf(1, 1),
+			score:      1,
+			wantLegacy: -0.6,
+			wantNormalized: func(t *testing.T, weight float32) {
+				assert.InDelta(t, -0.6, weight, 1e-6)
+			},
+		},
+		{
+			name:       "Constant scores stay within bounds",
+			stats:      statsOf(3, 3, 3, 3),
+			score:      5,
+			wantLegacy: 1,
+			wantNormalized: func(t *testing.T, weight float32) {
+				assert.Equal(t, float32(1), weight)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.wantLegacy, tasteWeight(LegacyTasteWeighting, tt.stats, tt.score), 1e-6)
+			tt.wantNormalized(t, tasteWeight(NormalizedTasteWeighting, tt.stats, tt.score))
+		})
+	}
+}
+
+func TestParseTasteWeighting(t *testing.T) {
+	weighting, err := ParseTasteWeighting("")
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyTasteWeighting, weighting)
+
+	weighting, err = ParseTasteWeighting("normalized")
+	assert.NoError(t, err)
+	assert.Equal(t, NormalizedTasteWeighting, weighting)
+
+	_, err = ParseTasteWeighting("zscore")
+	assert.Error(t, err)
+}
+
+func TestRatingService_RateMovie_TasteWeighting(t *testing.T) {
+	ctx := context.Background()
+	movieID := uuid.New()
+	embedding := make([]float32, 512)
+	embedding[0] = 1
+
+	rate := func(weighting TasteWeighting) *models.User {
+		mockRatingRepo := new(MockRatingRepository)
+		mockMovieRepo := new(MockMovieRepository)
+		service := NewRatingService(mockRatingRepo, mockMovieRepo, new(MockUserRepository))
+		service.SetTasteWeighting(weighting)
+
+		mockMovieRepo.On("GetByID", ctx, movieID).Return(&models.Movie{ID: movieID, Embedding: pgvector.NewVector(embedding)}, nil)
+		mockRatingRepo.On("GetByUserAndMovie", ctx, mock.AnythingOfType("uuid.UUID"), movieID).Return(nil, nil)
+		mockRatingRepo.On("SaveBatch", ctx, mock.AnythingOfType("*models.User"), mock.AnythingOfType("[]*models.Rating")).Return(nil)
+
+		// A harsh critic whose 2.5 is well above their own average
+		user := &models.User{
+			ID:         uuid.New(),
+			Taste:      pgvector.NewVector(make([]float32, 512)),
+			ScoreStats: statsOf(1, 1.5, 1.5, 2, 2),
+		}
+		_, err := service.RateMovie(ctx, user, movieID, 2.5)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, user.ScoreStats.Count)
+		return user
+	}
+
+	legacy := rate(LegacyTasteWeighting)
+	normalized := rate(NormalizedTasteWeighting)
+
+	// Legacy weighting treats 2.5 as neutral and leaves the taste untouched
+	assert.Equal(t, float32(0), legacy.Taste.Slice()[0])
+	assert.False(t, math.IsNaN(float64(legacy.Taste.Slice()[0])))
+	// Normalized weighting moves the taste towards the movie
+	assert.InDelta(t, 1, normalized.Taste.Slice()[0], 1e-6)
+}
@@ -2,31 +2,65 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/policy"
 	"github.com/Azanul/Next-Watch/internal/repository"
 	"github.com/google/uuid"
 )
 
+// Number of genres shown on a user's profile
+const TopGenresLimit = 3
+
 type UserService struct {
-	userRepo repository.UserRepositoryInterface
+	userRepo   repository.UserRepositoryInterface
+	ratingRepo repository.RatingRepositoryInterface
+	policy     *policy.Policy
 }
 
-func NewUserService(userRepo repository.UserRepositoryInterface) *UserService {
+func NewUserService(userRepo repository.UserRepositoryInterface, ratingRepo repository.RatingRepositoryInterface) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		ratingRepo: ratingRepo,
+		policy:     policy.Default(),
 	}
 }
 
+// SetPolicy replaces the default role permissions, e.g. with ones loaded from a roles file
+func (s *UserService) SetPolicy(p *policy.Policy) {
+	s.policy = p
+}
+
+// CreateUser stores a new user and fills in the generated fields of user, such as its ID
 func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
 	userID := uuid.New()
 
-	return s.userRepo.Create(ctx, &models.User{
-		ID:    userID,
-		Email: user.Email,
-		Name:  user.Name,
-		Role:  "USER",
-	})
+	newUser := &models.User{
+		ID:              userID,
+		Email:           user.Email,
+		Name:            user.Name,
+		AvatarURL:       user.AvatarURL,
+		Role:            string(policy.RoleUser),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+	if err := s.userRepo.Create(ctx, newUser); err != nil {
+		return err
+	}
+	*user = *newUser
+	return nil
+}
+
+func (s *UserService) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// GetUsersByIDs returns the users that exist out of ids, in no particular order
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	return s.userRepo.GetByIDs(ctx, ids)
 }
 
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -36,3 +70,111 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models
 func (s *UserService) UpdateUser(ctx context.Context, user *models.User) error {
 	return s.userRepo.Update(ctx, user)
 }
+
+func (s *UserService) GetRatingSummary(ctx context.Context, userID uuid.UUID) (*repository.RatingSummary, error) {
+	return s.ratingRepo.GetSummaryByUser(ctx, userID)
+}
+
+func (s *UserService) GetRatingSummaries(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*repository.RatingSummary, error) {
+	return s.ratingRepo.GetSummariesByUsers(ctx, userIDs)
+}
+
+func (s *UserService) GetTopGenres(ctx context.Context, userID uuid.UUID) ([]*repository.GenreSummary, error) {
+	return s.ratingRepo.GetTopGenresByUser(ctx, userID, TopGenresLimit)
+}
+
+func (s *UserService) SearchUsers(ctx context.Context, searchTerm string, page, pageSize int) (*repository.UserPage, error) {
+	return s.userRepo.Search(ctx, searchTerm, page, pageSize)
+}
+
+// ChangeRole gives a user a new role. Admins cannot change their own role so they can't lock themselves out.
+func (s *UserService) ChangeRole(ctx context.Context, admin *models.User, userID uuid.UUID, role string) (*models.User, error) {
+	if !s.policy.HasRole(policy.Role(role)) {
+		return nil, apperr.Validation(fmt.Sprintf("unknown role %q", role))
+	}
+	if admin.ID == userID {
+		return nil, apperr.Forbidden("cannot change your own role")
+	}
+
+	user, err := s.getExistingUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+func (s *UserService) SuspendUser(ctx context.Context, admin *models.User, userID uuid.UUID) (*models.User, error) {
+	if admin.ID == userID {
+		return nil, apperr.Forbidden("cannot suspend yourself")
+	}
+
+	user, err := s.getExistingUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return user, nil
+	}
+
+	suspendedAt := time.Now()
+	if err := s.userRepo.UpdateSuspension(ctx, userID, &suspendedAt); err != nil {
+		return nil, err
+	}
+	user.SuspendedAt = &suspendedAt
+	return user, nil
+}
+
+func (s *UserService) ReactivateUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.getExistingUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt == nil {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateSuspension(ctx, userID, nil); err != nil {
+		return nil, err
+	}
+	user.SuspendedAt = nil
+	return user, nil
+}
+
+// DeleteAccount removes the user's own account with everything stored about them.
+// The user confirms by typing their email address.
+func (s *UserService) DeleteAccount(ctx context.Context, user *models.User, confirmEmail string) error {
+	if !strings.EqualFold(strings.TrimSpace(confirmEmail), user.Email) {
+		return apperr.Validation("confirmation does not match the account email")
+	}
+	deleted, err := s.userRepo.Delete(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return apperr.NotFound("user not found")
+	}
+	return nil
+}
+
+// DeleteUser removes a user and all of their ratings
+func (s *UserService) DeleteUser(ctx context.Context, admin *models.User, userID uuid.UUID) (bool, error) {
+	if admin.ID == userID {
+		return false, apperr.Forbidden("cannot delete yourself")
+	}
+	return s.userRepo.Delete(ctx, userID)
+}
+
+func (s *UserService) getExistingUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	return user, nil
+}
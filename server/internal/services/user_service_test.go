@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/repository"
 	"github.com/google/uuid"
 	"github.com/pgvector/pgvector-go"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +23,22 @@ func (m *MockUserRepository) Create(ctx context.Context, user *models.User) erro
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -34,9 +52,42 @@ func (m *MockUserRepository) Update(ctx context.Context, user *models.User) erro
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Search(ctx context.Context, searchTerm string, page, pageSize int) (*repository.UserPage, error) {
+	args := m.Called(ctx, searchTerm, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.UserPage), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string) error {
+	args := m.Called(ctx, id, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateSuspension(ctx context.Context, id uuid.UUID, suspendedAt *time.Time) error {
+	args := m.Called(ctx, id, suspendedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	args := m.Called(ctx, id, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	tests := []struct {
 		name      string
@@ -90,7 +141,7 @@ func TestUserService_CreateUser(t *testing.T) {
 
 func TestUserService_GetUserByEmail(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	tests := []struct {
 		name      string
@@ -152,7 +203,7 @@ func TestUserService_GetUserByEmail(t *testing.T) {
 
 func TestUserService_UpdateUser(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	tests := []struct {
 		name      string
@@ -203,3 +254,197 @@ func TestUserService_UpdateUser(t *testing.T) {
 		mockRepo.Calls = nil
 	}
 }
+
+func TestUserService_GetUserByID(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil)
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		mockSetup func()
+		want      *models.User
+		wantErr   bool
+	}{
+		{
+			name: "Success",
+			mockSetup: func() {
+				mockRepo.On("GetByID", mock.Anything, userID).Return(&models.User{ID: userID, Name: "Test User"}, nil)
+			},
+			want:    &models.User{ID: userID, Name: "Test User"},
+			wantErr: false,
+		},
+		{
+			name: "Not Found",
+			mockSetup: func() {
+				mockRepo.On("GetByID", mock.Anything, userID).Return(nil, nil)
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mockSetup: func() {
+				mockRepo.On("GetByID", mock.Anything, userID).Return(nil, errors.New("database error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := service.GetUserByID(context.Background(), userID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserService.GetUserByID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+		mockRepo.ExpectedCalls = nil
+		mockRepo.Calls = nil
+	}
+}
+
+func TestUserService_GetRatingSummaryAndTopGenres(t *testing.T) {
+	mockRatingRepo := new(MockRatingRepository)
+	service := NewUserService(nil, mockRatingRepo)
+	userID := uuid.New()
+
+	mockRatingRepo.On("GetSummaryByUser", mock.Anything, userID).Return(&repository.RatingSummary{Count: 2, AverageScore: 3.5}, nil)
+	mockRatingRepo.On("GetTopGenresByUser", mock.Anything, userID, TopGenresLimit).Return([]*repository.GenreSummary{{Genre: "drama", Count: 2, AverageScore: 3.5}}, nil)
+
+	summary, err := service.GetRatingSummary(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, summary.Count)
+
+	genres, err := service.GetTopGenres(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "drama", genres[0].Genre)
+}
+
+func TestUserService_ChangeRole(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Role: "ADMIN"}
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		userID    uuid.UUID
+		role      string
+		mockSetup func(mockRepo *MockUserRepository)
+		wantErr   bool
+	}{
+		{
+			name:   "Success",
+			userID: userID,
+			role:   "ADMIN",
+			mockSetup: func(mockRepo *MockUserRepository) {
+				mockRepo.On("GetByID", mock.Anything, userID).Return(&models.User{ID: userID, Role: "USER"}, nil)
+				mockRepo.On("UpdateRole", mock.Anything, userID, "ADMIN").Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:      "Error - Unknown Role",
+			userID:    userID,
+			role:      "SUPERUSER",
+			mockSetup: func(mockRepo *MockUserRepository) {},
+			wantErr:   true,
+		},
+		{
+			name:      "Error - Role Not In Policy",
+			userID:    userID,
+			role:      "CURATOR",
+			mockSetup: func(mockRepo *MockUserRepository) {},
+			wantErr:   true,
+		},
+		{
+			name:      "Error - Own Role",
+			userID:    admin.ID,
+			role:      "USER",
+			mockSetup: func(mockRepo *MockUserRepository) {},
+			wantErr:   true,
+		},
+		{
+			name:   "Error - Not Found",
+			userID: userID,
+			role:   "USER",
+			mockSetup: func(mockRepo *MockUserRepository) {
+				mockRepo.On("GetByID", mock.Anything, userID).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			tt.mockSetup(mockRepo)
+			service := NewUserService(mockRepo, nil)
+
+			got, err := service.ChangeRole(context.Background(), admin, tt.userID, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserService.ChangeRole() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, tt.role, got.Role)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_SuspendAndReactivateUser(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Role: "ADMIN"}
+	userID := uuid.New()
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil)
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil).Once()
+	mockRepo.On("UpdateSuspension", mock.Anything, userID, mock.AnythingOfType("*time.Time")).Return(nil).Once()
+	user, err := service.SuspendUser(context.Background(), admin, userID)
+	assert.NoError(t, err)
+	assert.NotNil(t, user.SuspendedAt)
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(user, nil).Once()
+	mockRepo.On("UpdateSuspension", mock.Anything, userID, (*time.Time)(nil)).Return(nil).Once()
+	user, err = service.ReactivateUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Nil(t, user.SuspendedAt)
+
+	_, err = service.SuspendUser(context.Background(), admin, admin.ID)
+	assert.Error(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Role: "ADMIN"}
+	userID := uuid.New()
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil)
+
+	mockRepo.On("Delete", mock.Anything, userID).Return(true, nil)
+	deleted, err := service.DeleteUser(context.Background(), admin, userID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = service.DeleteUser(context.Background(), admin, admin.ID)
+	assert.Error(t, err)
+	assert.False(t, deleted)
+}
+
+func TestUserService_DeleteAccount(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo, nil)
+
+	err := service.DeleteAccount(context.Background(), user, "someone@example.com")
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	mockRepo.On("Delete", mock.Anything, user.ID).Return(true, nil)
+	assert.NoError(t, service.DeleteAccount(context.Background(), user, " Test@Example.com "))
+}
@@ -2,82 +2,272 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/Azanul/Next-Watch/graph"
+	"github.com/Azanul/Next-Watch/internal/apperr"
 	"github.com/Azanul/Next-Watch/internal/auth"
+	"github.com/Azanul/Next-Watch/internal/config"
 	"github.com/Azanul/Next-Watch/internal/database"
 	"github.com/Azanul/Next-Watch/internal/handlers"
+	"github.com/Azanul/Next-Watch/internal/mailer"
+	"github.com/Azanul/Next-Watch/internal/models"
+	"github.com/Azanul/Next-Watch/internal/persisted"
+	"github.com/Azanul/Next-Watch/internal/policy"
+	"github.com/Azanul/Next-Watch/internal/pubsub"
+	"github.com/Azanul/Next-Watch/internal/querylimit"
 	"github.com/Azanul/Next-Watch/internal/repository"
 	"github.com/Azanul/Next-Watch/internal/services"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
-const defaultPort = "8080"
+// How often abandoned sign-ins are removed from the state store
+const stateSweepInterval = 5 * time.Minute
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	// Loaded before the log file is opened, so configuration errors are printed to the terminal
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	logFile := initLogFile()
+	logFile := initLogFile(cfg.LogFile)
 	defer logFile.Close()
 
-	db := database.ConnectDB()
+	db := database.ConnectDB(cfg.DatabaseURL)
 
+	// Rating changes reach the subscriptions of this replica only
+	mux, err := newServer(context.Background(), cfg, db, pubsub.NewMemory())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal(http.ListenAndServe(":"+cfg.Port, mux))
+}
+
+// newServer wires the repositories, services and handlers of the app on db. Rating changes are announced
+// to subscriptions through ps, and abandoned sign-ins are swept until ctx is done.
+func newServer(ctx context.Context, cfg *config.Config, db *sql.DB, ps pubsub.PubSub) (*http.ServeMux, error) {
 	userRepo := repository.NewUserRepository(db)
 	movieRepo := repository.NewMovieRepository(db)
 	ratingRepo := repository.NewRatingRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	accessTokenRepo := repository.NewAccessTokenRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	accountTokenRepo := repository.NewAccountTokenRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
 
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(userRepo, ratingRepo)
 	movieService := services.NewMovieService(movieRepo)
 	ratingService := services.NewRatingService(ratingRepo, movieRepo, userRepo)
 	recommendationService := services.NewRecommendationService(ratingRepo, movieRepo)
+	importService := services.NewImportService(ratingRepo, movieRepo)
+
+	rolePolicy, err := policy.Load(cfg.RolesFile)
+	if err != nil {
+		return nil, err
+	}
+	userService.SetPolicy(rolePolicy)
+	ratingService.SetTasteWeighting(cfg.TasteWeighting)
+	importService.SetTasteWeighting(cfg.TasteWeighting)
+
+	liveService := services.NewLiveService(ps, ratingRepo)
+	ratingService.SetLiveService(liveService)
+	importService.SetLiveService(liveService)
+	exportService := services.NewExportService(ratingRepo)
+	dataExportService := services.NewDataExportService(exportService, userRepo, sessionRepo, accessTokenRepo, userIdentityRepo, dataExportRepo)
+
+	sessionService := services.NewSessionService(sessionRepo, userRepo)
+	if err := sessionService.SetTimeouts(cfg.Session.IdleTimeout, cfg.Session.AbsoluteTimeout); err != nil {
+		return nil, err
+	}
+
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo, userRepo)
+
+	mail, err := mailerFromConfig(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	accountService := services.NewAccountService(userRepo, accountTokenRepo, mail, cfg.PublicURL)
+	identityService := services.NewIdentityService(userIdentityRepo, userService, accountService)
+
+	// Sign-in state lives in Postgres so any replica can handle the OAuth callback,
+	// OAUTH_STATE_STORE=memory keeps it in process for single instance setups
+	var stateStore auth.StateStore = auth.NewPostgresStateStore(db, cfg.Keyring)
+	if cfg.OAuthStateStore == "memory" {
+		stateStore = auth.NewMemoryStateStore()
+	}
+	go auth.RunStateSweeper(ctx, stateStore, stateSweepInterval)
+
+	// Google is configured through its own variables, any other OpenID Connect provider through AUTH_PROVIDERS_FILE
+	identityProviders, err := auth.LoadOIDCProviders(ctx, cfg.AuthProvidersFile, stateStore)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Google.ClientID != "" {
+		// Refresh tokens can only be kept sealed, so they are only asked for with ENCRYPTION_KEYS set
+		google := cfg.Google
+		google.OfflineAccess = cfg.Keyring != nil
+		identityProviders = append(identityProviders, auth.NewGoogleAuthClient(google, stateStore))
+	}
+	providerRegistry, err := auth.NewProviderRegistry(identityProviders...)
+	if err != nil {
+		return nil, err
+	}
+	identityService.SetRefreshTokens(cfg.Keyring, providerRegistry)
+
+	resolver := &graph.Resolver{
+		RatingService: *ratingService, MovieService: *movieService, RecommendationService: *recommendationService,
+		ImportService: *importService, UserService: *userService,
+		SessionService: *sessionService, AccessTokenService: *accessTokenService, DataExportService: *dataExportService,
+		IdentityService: *identityService, LiveService: liveService,
+		Policy: rolePolicy,
+	}
+	restHandler := handlers.NewHandler(userService, sessionService, accessTokenService, exportService, dataExportService, accountService, identityService, providerRegistry)
+	restHandler.SetSecureCookies(cfg.Session.SecureCookies)
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(
+	srv := handler.New(graph.NewExecutableSchema(
 		graph.Config{
-			Resolvers: &graph.Resolver{
-				RatingService: *ratingService, MovieService: *movieService, RecommendationService: *recommendationService,
-			},
+			Resolvers:  resolver,
+			Complexity: graph.Complexity(),
 			Directives: graph.DirectiveRoot{
-				HasRole: hasRoleDirective,
+				Auth:          authDirective,
+				HasPermission: hasPermissionDirective(rolePolicy),
 			},
 		},
 	))
-	restHandler := handlers.NewHandler(userService, auth.NewGoogleAuthClient())
+	// The upgrade request passes through OptionalAuthMiddleware like any other, so browsers are signed in by
+	// their cookie. The upgrader's default origin check refuses websockets opened by other sites.
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              restHandler.WebsocketInit,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(apperr.Presenter)
+	srv.SetRecoverFunc(apperr.Recover)
+	srv.Use(extension.Introspection{})
+	srv.Use(persistedQueriesFromConfig(cfg.PersistedQueries, db))
+	srv.Use(querylimit.New(cfg.QueryLimits.MaxDepth, cfg.QueryLimits.MaxCost))
+	srv.AroundOperations(tokenScopeMiddleware)
+	srv.AroundResponses(resolver.LoaderMiddleware)
 
-	http.HandleFunc("/auth/signin/google", cors(restHandler.GoogleSignin))
-	http.HandleFunc("/auth/callback/google", restHandler.GoogleCallback)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/signin/{provider}", cors(cfg.CORSOrigins, restHandler.Signin))
+	mux.HandleFunc("/auth/callback/{provider}", restHandler.Callback)
+	mux.HandleFunc("/auth/link/{provider}", restHandler.AuthMiddleware(http.HandlerFunc(restHandler.LinkProvider)).ServeHTTP)
+	mux.HandleFunc("/auth/logout", cors(cfg.CORSOrigins, restHandler.Logout))
+	mux.HandleFunc("/auth/error", restHandler.AuthError)
+	mux.HandleFunc("/auth/local/signup", cors(cfg.CORSOrigins, restHandler.SignUp))
+	mux.HandleFunc("/auth/local/signin", cors(cfg.CORSOrigins, restHandler.LocalSignin))
+	mux.HandleFunc("/auth/local/verify", restHandler.VerifyEmail)
+	mux.HandleFunc("/auth/local/reset-request", cors(cfg.CORSOrigins, restHandler.RequestPasswordReset))
+	mux.HandleFunc("/auth/local/reset", restHandler.ResetPassword)
 
-	http.HandleFunc("/query", cors(http.HandlerFunc(restHandler.AuthMiddleware(srv).ServeHTTP)))
-	http.Handle("/", http.FileServer(getFrontendFileSystem()))
+	// Catalog queries are public, fields that need a user are guarded by @auth and @hasPermission
+	mux.HandleFunc("/query", cors(cfg.CORSOrigins, restHandler.OptionalAuthMiddleware(srv).ServeHTTP))
+	mux.HandleFunc("/export", cors(cfg.CORSOrigins, restHandler.AuthMiddleware(http.HandlerFunc(restHandler.Export)).ServeHTTP))
+	mux.HandleFunc("/export/archive", restHandler.ExportArchive)
+	mux.Handle("/", http.FileServer(getFrontendFileSystem()))
+	return mux, nil
+}
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+// authDirective rejects anonymous requests with an UNAUTHENTICATED error
+func authDirective(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, err := auth.RequireUser(ctx); err != nil {
+		return nil, apperr.Unauthenticated(err.Error())
+	}
+	return next(ctx)
 }
 
-func hasRoleDirective(ctx context.Context, obj interface{}, next graphql.Resolver, role string) (interface{}, error) {
-	user, err := auth.GetUserFromContext(ctx)
-	if err != nil {
-		return nil, err
+func hasPermissionDirective(p *policy.Policy) func(ctx context.Context, obj interface{}, next graphql.Resolver, permission string) (interface{}, error) {
+	return func(ctx context.Context, obj interface{}, next graphql.Resolver, permission string) (interface{}, error) {
+		// A typo in the schema must not open the field to everyone
+		if !policy.IsPermission(permission) {
+			return nil, fmt.Errorf("unknown permission %q", permission)
+		}
+
+		user, err := auth.RequireUser(ctx)
+		if err != nil {
+			return nil, apperr.Unauthenticated(err.Error())
+		}
+
+		// Check if the user's role grants the permission
+		if !p.Can(user, policy.Permission(permission)) {
+			return nil, apperr.Forbidden("access denied")
+		}
+
+		// If the user has the permission, continue to the next resolver
+		return next(ctx)
 	}
+}
 
-	// Check if the user has the required role
-	if user.Role != role {
-		return nil, errors.New("access denied")
+// tokenScopeMiddleware limits requests made with a personal access token to what its scopes allow
+func tokenScopeMiddleware(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	scope := models.ScopeRead
+	if graphql.GetOperationContext(ctx).Operation.Operation == ast.Mutation {
+		scope = models.ScopeWrite
 	}
 
-	// If the user has the role, continue to the next resolver
+	if !auth.HasScope(ctx, scope) {
+		return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{{
+			Message:    fmt.Sprintf("access token lacks the %s scope", scope),
+			Extensions: map[string]interface{}{"code": apperr.CodeForbidden},
+		}}})
+	}
 	return next(ctx)
 }
 
-func cors(h http.HandlerFunc) http.HandlerFunc {
+// mailerFromConfig builds the mailer picked with MAILER
+func mailerFromConfig(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "file":
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return mailer.LogMailer{}, nil
+	}
+}
+
+// persistedQueriesFromConfig builds the persisted query extension picked with PERSISTED_QUERY_STORE
+func persistedQueriesFromConfig(cfg config.PersistedQueriesConfig, db *sql.DB) graphql.HandlerExtension {
+	if cfg.Store != "postgres" {
+		return extension.AutomaticPersistedQuery{Cache: lru.New[string](persisted.DefaultCacheSize)}
+	}
+	store := persisted.NewPostgresStore(db, persisted.DefaultCacheSize)
+	if cfg.Only {
+		return persisted.Allowlist{Registry: store}
+	}
+	return extension.AutomaticPersistedQuery{Cache: store}
+}
+
+// cors allows the frontends at origins to call h with their cookies
+func cors(origins []string, h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:64139")
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); slices.Contains(origins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -91,13 +281,16 @@ func cors(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// Function to initialize the log file and set the log output to the file
-func initLogFile() *os.File {
-	logFile, err := os.OpenFile("app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+// Function to initialize the log file and set the log output to the file, "-" keeps logging to stderr
+func initLogFile(path string) *os.File {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	if path == "-" {
+		return os.Stderr
+	}
+	logFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatalf("Failed to open log file: %v", err)
 	}
 	log.SetOutput(logFile)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	return logFile
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/Azanul/Next-Watch/internal/config"
+	"github.com/Azanul/Next-Watch/internal/globalid"
+	"github.com/Azanul/Next-Watch/internal/pubsub"
+	"github.com/Azanul/Next-Watch/internal/repository"
+	"github.com/Azanul/Next-Watch/internal/services"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer_MovieRatingStatsSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.Config{
+		OAuthStateStore:  "memory",
+		Session:          config.SessionConfig{IdleTimeout: services.DefaultSessionIdleTimeout, AbsoluteTimeout: services.DefaultSessionAbsoluteTimeout},
+		Mail:             config.MailConfig{Mailer: "log"},
+		PersistedQueries: config.PersistedQueriesConfig{Store: "memory"},
+		QueryLimits:      config.QueryLimitsConfig{MaxDepth: 10, MaxCost: 5000},
+	}
+	ps := pubsub.NewMemory()
+	mux, err := newServer(ctx, cfg, db, ps)
+	require.NoError(t, err)
+
+	movieID := uuid.New()
+	mock.ExpectQuery("^SELECT (.+) FROM movies WHERE id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "genre", "year", "wiki", "plot", "cast", "embedding"}).
+			AddRow(movieID, "Heat", "Crime", 1995, "", "", "", pgvector.NewVector([]float32{1, 2, 3})))
+	mock.ExpectQuery("^SELECT COUNT(.+) FROM ratings WHERE movie_id").
+		WithArgs(movieID).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "avg"}).AddRow(3, 4.5))
+
+	subscription := client.New(mux, client.Path("/query")).Websocket(
+		`subscription($movieId: ID!) { movieRatingStatsChanged(movieId: $movieId) { movieId ratingCount averageScore } }`,
+		client.Var("movieId", globalid.Encode(globalid.Movie, movieID)),
+	)
+	defer subscription.Close()
+
+	// Someone rating the movie, as announced by the rating service of any request
+	announcer := services.NewLiveService(ps, repository.NewRatingRepository(db))
+	go func() {
+		// The subscription may not be listening yet, so keep announcing until the test is done
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			announcer.RatingsChanged(ctx, uuid.New(), movieID)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var resp struct {
+		MovieRatingStatsChanged struct {
+			MovieID      string
+			RatingCount  int
+			AverageScore float64
+		}
+	}
+	require.NoError(t, subscription.Next(&resp))
+	assert.Equal(t, globalid.Encode(globalid.Movie, movieID), resp.MovieRatingStatsChanged.MovieID)
+	assert.Equal(t, 3, resp.MovieRatingStatsChanged.RatingCount)
+	assert.Equal(t, 4.5, resp.MovieRatingStatsChanged.AverageScore)
+}